@@ -1,44 +1,162 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
 	"github.com/maevlava/chirpy/internal/config"
 	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/database/migrate"
+	"github.com/maevlava/chirpy/internal/database/sqlite"
 	httpdelivery "github.com/maevlava/chirpy/internal/delivery/http"
-	"log"
-	"net/http"
+	"github.com/maevlava/chirpy/internal/metrics"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-const defaultPort = "8080"
-
 func main() {
 	err := godotenv.Load()
 	if err != nil {
-		_ = fmt.Errorf("Error loading .env file")
+		slog.Info("no .env file loaded", "err", err)
 	}
 
-	cfg := config.Load()
-	loadDB(cfg)
+	args := os.Args[1:]
+	switch {
+	case len(args) == 0:
+		err = run()
+	case args[0] == "migrate":
+		err = runMigrate(args[1:])
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+const usage = `usage:
+  chirpy                             start the server
+  chirpy migrate up|down|status      manage the database schema`
+
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(cfg.Logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg.Metrics = metrics.New()
+	db, err := loadDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if cfg.Database.AutoMigrate {
+		migrator, err := migrate.New(db, cfg.Database.Driver)
+		if err != nil {
+			return err
+		}
+		err = migrator.Apply(ctx, cfg.Logger)
+		if err != nil {
+			return err
+		}
+	}
 
 	appInstance := app.NewApplication(cfg)
 
 	router := httpdelivery.NewRouter(appInstance)
-	server := http.Server{
-		Addr:    ":" + defaultPort,
-		Handler: router,
+	return serve(ctx, stop, cfg, router)
+}
+
+// loadDB opens the database and wires the stores that depend on it,
+// reporting queries and pool stats to cfg.Metrics. The caller owns the
+// returned handle and must close it.
+func loadDB(ctx context.Context, cfg *config.ApiConfig) (*sql.DB, error) {
+	db, err := openDB(ctx, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Metrics.RegisterDB(db, cfg.Database.Driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	switch cfg.Database.Driver {
+	case database.DriverSQLite:
+		cfg.DB = sqlite.NewObservedStore(db, cfg.Metrics.ObserveQuery)
+	default:
+		cfg.DB = database.NewObservedStore(db, cfg.Metrics.ObserveQuery)
+	}
+	cfg.DBPinger = db
+	cfg.TokenDenylist = auth.NewStoreDenylist(cfg.DB, cfg.AccessTokenTTL)
+	return db, nil
+}
+
+// openDB opens a connection pool for the configured driver and waits until
+// the database answers.
+func openDB(ctx context.Context, cfg config.DatabaseConfig) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch cfg.Driver {
+	case database.DriverSQLite:
+		db, err = sqlite.Open(cfg.URL)
+	default:
+		db, err = sql.Open("postgres", cfg.URL)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	fmt.Println("Server listening on port ", defaultPort)
-	log.Fatal(server.ListenAndServe())
+	err = pingWithRetry(ctx, db, cfg.ConnectTimeout)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
-func loadDB(cfg *config.ApiConfig) {
-	dbURL := os.Getenv("DB_URL")
-	db, _ := sql.Open("postgres", dbURL)
-	cfg.DB = database.New(db)
+// pingWithRetry pings db with exponential backoff until it answers or
+// timeout passes, so the server can start alongside its database, e.g. in
+// docker compose, instead of failing on the first refused connection.
+func pingWithRetry(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	const (
+		initialBackoff = 250 * time.Millisecond
+		maxBackoff     = 5 * time.Second
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("database not ready", "attempt", attempt, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/database/migrate"
+)
+
+// runMigrate implements `chirpy migrate up|down|status`.
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("migrate needs exactly one of up, down or status\n\n%s", usage)
+	}
+
+	// the server's secrets are not needed to migrate
+	cfg, err := config.LoadDatabase()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := openDB(ctx, *cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, cfg.Driver)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx, os.Stdout)
+	case "down":
+		return migrator.Down(ctx, os.Stdout)
+	case "status":
+		return migrator.Status(ctx, os.Stdout)
+	}
+	return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], usage)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+// newServer returns the API server for handler. With TLS configured it
+// only accepts TLS 1.2 and later.
+func newServer(cfg config.ServerConfig, handler http.Handler, logger *slog.Logger) *http.Server {
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	if cfg.TLS() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return server
+}
+
+// newRedirectServer returns the plain HTTP server sending clients to the
+// HTTPS port.
+func newRedirectServer(cfg config.ServerConfig, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.HTTPRedirectPort),
+		Handler:           httputil.RedirectToHTTPS(cfg.Port),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+}
+
+// serve runs the API server, and the redirect server when configured,
+// until one fails or ctx is done, then drains in-flight requests for up to
+// the shutdown timeout. stop releases the signal handling behind ctx so a
+// second signal during the drain kills the process right away.
+func serve(ctx context.Context, stop context.CancelFunc, cfg *config.ApiConfig, handler http.Handler) error {
+	servers := []*http.Server{newServer(cfg.Server, handler, cfg.Logger)}
+	if cfg.Server.HTTPRedirectPort != 0 {
+		servers = append(servers, newRedirectServer(cfg.Server, cfg.Logger))
+	}
+
+	serverErr := make(chan error, len(servers))
+	go func() {
+		server := servers[0]
+		slog.Info("server listening", "port", cfg.Server.Port, "tls", cfg.Server.TLS())
+		if cfg.Server.TLS() {
+			serverErr <- server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+			return
+		}
+		serverErr <- server.ListenAndServe()
+	}()
+	if len(servers) > 1 {
+		go func() {
+			slog.Info("redirecting http to https", "port", cfg.Server.HTTPRedirectPort)
+			serverErr <- servers[1].ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-serverErr:
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutting down, draining requests", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		shutdownErr := server.Shutdown(shutdownCtx)
+		if shutdownErr != nil {
+			return fmt.Errorf("graceful shutdown failed: %w", shutdownErr)
+		}
+	}
+	if err != nil {
+		// a server failed to start or stopped on its own
+		return err
+	}
+	for range servers {
+		if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	slog.Info("server stopped")
+	return nil
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+type APIKeyResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  string    `json:"created_at"`
+	LastUsedAt string    `json:"last_used_at,omitempty"`
+	// Key is only set in the response to creation.
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key database.ApiKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    auth.SplitScopes(key.Scopes),
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.LastUsedAt.Valid {
+		response.LastUsedAt = key.LastUsedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+// API keys are managed with a login session only, so a leaked key cannot be
+// used to mint further keys.
+
+func (app *Application) HandlerCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.authenticate(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params, err := httputil.DecodeJSON[CreateAPIKeyRequest](w, r)
+	if err != nil {
+		httputil.RespondWithDecodeError(w, err)
+		return
+	}
+	scopes, err := auth.ParseScopes(params.Scopes)
+	if err != nil {
+		httputil.RespondWithValidationErrors(w, []httputil.FieldError{{
+			Field:   "scopes",
+			Code:    "invalid_scope",
+			Message: err.Error(),
+		}})
+		return
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		httputil.RespondWithInternalError(w, r, err)
+		return
+	}
+	created, err := app.Config.DB.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		ID:      uuid.New(),
+		UserID:  userID,
+		Name:    params.Name,
+		Prefix:  prefix,
+		KeyHash: auth.HashAPIKey(key),
+		Scopes:  auth.JoinScopes(scopes),
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("storing api key", "user_id", userID, "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not create API key")
+		return
+	}
+
+	response := newAPIKeyResponse(created)
+	response.Key = key
+	httputil.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (app *Application) HandlerListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.authenticate(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	keys, err := app.Config.DB.ListAPIKeysByUser(r.Context(), userID)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not list API keys")
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+	httputil.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (app *Application) HandlerRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.authenticate(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	keyID, err := uuid.Parse(r.PathValue("keyId"))
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusBadRequest, "API key ID is invalid")
+		return
+	}
+
+	revoked, err := app.Config.DB.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not revoke API key")
+		return
+	}
+	if revoked == 0 {
+		httputil.RespondWithError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,15 +1,72 @@
 package app
 
 import (
+	"log/slog"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
 	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/mail"
+	"github.com/maevlava/chirpy/internal/metrics"
+	"github.com/maevlava/chirpy/internal/ratelimit"
+	"github.com/maevlava/chirpy/internal/service"
+)
+
+// Token lifetimes used when the config leaves them unset.
+const (
+	AccessTokenDuration  = 1 * time.Hour
+	RefreshTokenDuration = 60 * 24 * time.Hour
 )
 
 type Application struct {
-	Config *config.ApiConfig
+	Config    *config.ApiConfig
+	Logger    *slog.Logger
+	Users     *service.UserService
+	Chirps    *service.ChirpService
+	Sessions  *service.SessionService
+	TwoFactor *service.TwoFactorService
+
+	keyOwners *keyOwners
 }
 
 func NewApplication(cfg *config.ApiConfig) *Application {
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = AccessTokenDuration
+	}
+	if cfg.RefreshTokenTTL == 0 {
+		cfg.RefreshTokenTTL = RefreshTokenDuration
+	}
+	if cfg.TokenDenylist == nil {
+		cfg.TokenDenylist = auth.NewMemoryDenylist(cfg.AccessTokenTTL)
+	}
+	if cfg.PasswordPolicy.MinLength == 0 && cfg.PasswordPolicy.MaxLength == 0 && cfg.PasswordPolicy.Banned == nil {
+		cfg.PasswordPolicy = auth.DefaultPasswordPolicy()
+	}
+	if cfg.PasswordHasher == nil {
+		cfg.PasswordHasher = auth.DefaultPasswordHasher()
+	}
+	if cfg.Mailer == nil {
+		cfg.Mailer = mail.LogMailer{}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.RateLimitStore == nil {
+		cfg.RateLimitStore = ratelimit.NewMemoryStore()
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.New()
+	}
+	if cfg.LoginThrottle == nil {
+		cfg.LoginThrottle = auth.NewLoginThrottle(auth.DefaultAccountLockout, auth.DefaultIPLockout)
+	}
 	return &Application{
-		Config: cfg,
+		Config:    cfg,
+		Logger:    cfg.Logger,
+		Users:     service.NewUserService(cfg.DB, cfg.PasswordPolicy, cfg.PasswordHasher, cfg.TokenDenylist),
+		Chirps:    service.NewChirpService(cfg.DB),
+		Sessions:  service.NewSessionService(cfg.DB, cfg.TokenDenylist, cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL),
+		TwoFactor: service.NewTwoFactorService(cfg.DB),
+		keyOwners: newKeyOwners(),
 	}
 }
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+// authenticate validates the bearer access token on the request and checks it
+// against the token denylist. It returns the user ID and the parsed claims.
+func (app *Application) authenticate(r *http.Request) (uuid.UUID, *jwt.RegisteredClaims, error) {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	claims, err := auth.ParseJWT(tokenString, app.Config.JWTSecret)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	userID, err := auth.UserIDFromClaims(claims)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	err = auth.CheckNotRevoked(r.Context(), app.Config.TokenDenylist, claims)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	logging.AddRequestAttrs(r.Context(), slog.String("user_id", userID.String()))
+	return userID, claims, nil
+}
+
+// RateLimitKey identifies the client of r for rate limiting: the user ID
+// when r carries a valid access token or a personal API key authorize has
+// already accepted, the client IP otherwise. A key counts against its
+// owner, so a bot cannot multiply its limit by minting keys. Nothing here
+// queries the database, so made-up keys are throttled by IP before they
+// reach it; tokens are not checked against the denylist either.
+func (app *Application) RateLimitKey(r *http.Request) string {
+	if apiKey, err := auth.GetAPIKey(r.Header); err == nil && auth.IsAPIKey(apiKey) {
+		if owner, ok := app.keyOwners.get(auth.HashAPIKey(apiKey)); ok {
+			return "user:" + owner.String()
+		}
+		return "ip:" + httputil.ClientIP(r)
+	}
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err == nil {
+		claims, err := auth.ParseJWT(tokenString, app.Config.JWTSecret)
+		if err == nil {
+			userID, err := auth.UserIDFromClaims(claims)
+			if err == nil {
+				return "user:" + userID.String()
+			}
+		}
+	}
+	return "ip:" + httputil.ClientIP(r)
+}
+
+var errMissingScope = errors.New("api key is missing the required scope")
+
+// authorize accepts either a bearer access token or a personal API key sent
+// as "Authorization: ApiKey ..." that holds one of the given scopes.
+func (app *Application) authorize(r *http.Request, scopes ...string) (uuid.UUID, error) {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		userID, _, err := app.authenticate(r)
+		return userID, err
+	}
+
+	if !auth.IsAPIKey(apiKey) {
+		return uuid.Nil, errors.New("invalid api key")
+	}
+	key, err := app.Config.DB.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(apiKey))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid api key")
+	}
+	granted := auth.SplitScopes(key.Scopes)
+	if !slices.ContainsFunc(scopes, func(scope string) bool { return slices.Contains(granted, scope) }) {
+		return uuid.Nil, errMissingScope
+	}
+
+	app.keyOwners.add(key.KeyHash, key.UserID)
+	err = app.Config.DB.TouchAPIKey(r.Context(), key.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("recording use of api key", "api_key_id", key.ID, "err", err)
+	}
+	logging.AddRequestAttrs(r.Context(),
+		slog.String("user_id", key.UserID.String()),
+		slog.String("api_key_id", key.ID.String()),
+	)
+	return key.UserID, nil
+}
+
+// authorizeRead checks the API key of a request to a public read route.
+// Anonymous and bearer requests pass, but a key must be valid and hold
+// chirps:read, or chirps:write which includes it, so a bot learns that its
+// key was revoked or is missing the scope.
+func (app *Application) authorizeRead(r *http.Request) error {
+	if _, err := auth.GetAPIKey(r.Header); err != nil {
+		return nil
+	}
+	_, err := app.authorize(r, auth.ScopeChirpsRead, auth.ScopeChirpsWrite)
+	return err
+}
+
+// respondAuthError maps an authorize error to 403 for a valid key lacking
+// the scope and 401 for everything else.
+func respondAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingScope) {
+		httputil.RespondWithCode(w, http.StatusForbidden, codeInsufficientScope, err.Error())
+		return
+	}
+	httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+}
+
+// maxKeyOwners bounds keyOwners; past it the cache starts over, which only
+// sends a key's next request to its client's IP bucket.
+const maxKeyOwners = 10_000
+
+// keyOwners remembers the owners of API keys authorize accepted, by key
+// hash, for RateLimitKey. An entry outliving its revoked key is harmless:
+// the request is limited by the former owner and then refused.
+type keyOwners struct {
+	mu     sync.Mutex
+	owners map[string]uuid.UUID
+}
+
+func newKeyOwners() *keyOwners {
+	return &keyOwners{owners: make(map[string]uuid.UUID)}
+}
+
+func (k *keyOwners) get(keyHash string) (uuid.UUID, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	owner, ok := k.owners[keyHash]
+	return owner, ok
+}
+
+func (k *keyOwners) add(keyHash string, owner uuid.UUID) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.owners[keyHash]; !ok && len(k.owners) >= maxKeyOwners {
+		clear(k.owners)
+	}
+	k.owners[keyHash] = owner
+}
@@ -1,17 +1,20 @@
 package app
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/config"
 	"github.com/maevlava/chirpy/internal/database"
 	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
-	"log"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/service"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,6 +32,18 @@ type RefreshTokenResponse struct {
 	Token string `json:"token"`
 }
 
+// errInvalidCredentials is the single login failure message, so responses
+// do not tell whether the email or the password was wrong.
+const errInvalidCredentials = "Incorrect email or password"
+
+// Error codes specific to authentication, on top of the generic ones in
+// httputil.
+const (
+	codeInvalidCredentials = "invalid_credentials"
+	codeAccountLocked      = "account_locked"
+	codeInsufficientScope  = "insufficient_scope"
+)
+
 func (app *Application) HandlerReadiness(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	_, err := w.Write([]byte("OK"))
@@ -41,14 +56,22 @@ func (app *Application) HandlerWebApp(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, indexPath)
 }
 
+// webAppRoutes are the routes of the web app whose visits the admin
+// metrics page counts.
+var webAppRoutes = []string{"/app", "/app/"}
+
 func (app *Application) HandlerMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	serverHits := app.Config.FileServerHits.Load()
+	serverHits, err := app.Config.Metrics.Requests(webAppRoutes...)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("gathering metrics", "err", err)
+		return
+	}
 
 	metricsPath := filepath.Join(app.Config.WebStaticDir, "admin/metrics.html")
 	htmlBytes, err := os.ReadFile(metricsPath)
 	if err != nil {
-		log.Printf("Error reading metrics file: %v", err)
+		logging.FromContext(r.Context()).Error("reading metrics file", "err", err)
 		return
 	}
 
@@ -56,38 +79,23 @@ func (app *Application) HandlerMetrics(w http.ResponseWriter, r *http.Request) {
 	htmlResponse := fmt.Sprintf(metricsHTMLContent, serverHits)
 	_, err = w.Write([]byte(htmlResponse))
 	if err != nil {
-		log.Printf("Error writing admin metrics response: %v", err)
+		logging.FromContext(r.Context()).Error("writing admin metrics response", "err", err)
 		return
 	}
 
 }
 
 func (app *Application) HandlerUsers(w http.ResponseWriter, r *http.Request) {
-	type EmailParam struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	decoder := json.NewDecoder(r.Body)
-	param := EmailParam{}
-	err := decoder.Decode(&param)
+	param, err := httputil.DecodeJSON[CreateUserRequest](w, r)
 	if err != nil {
-		_ = httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
-	}
-
-	hashedPassword, err := auth.HashPassword(param.Password)
-	newUser := database.CreateUserParams{
-		ID:             uuid.New(),
-		CreatedAt:      time.Now().UTC(),
-		UpdatedAt:      time.Now().UTC(),
-		Email:          param.Email,
-		HashedPassword: hashedPassword,
+		_ = httputil.RespondWithDecodeError(w, err)
+		return
 	}
 
-	db := app.Config.DB
-	createdUser, err := db.CreateUser(r.Context(), newUser)
+	createdUser, err := app.Users.Register(r.Context(), param.Email, param.Password)
 	if err != nil {
-		_ = httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		_ = respondUserError(w, r, err)
+		return
 	}
 
 	response := UserResponse{
@@ -100,56 +108,33 @@ func (app *Application) HandlerUsers(w http.ResponseWriter, r *http.Request) {
 	_ = httputil.RespondWithJSON(w, http.StatusCreated, response)
 }
 func (app *Application) HandlerResetUsers(w http.ResponseWriter, r *http.Request) {
-	platform := os.Getenv("PLATFORM")
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	if platform != "dev" {
+	if app.Config.Platform != config.PlatformDev {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	_ = app.Config.DB.DeleteAllUsers(r.Context())
+	_ = app.Users.DeleteAll(r.Context())
 	w.WriteHeader(http.StatusOK)
 
-	app.Config.FileServerHits.Store(0)
+	app.Config.Metrics.ResetRequests()
 }
 func (app *Application) HandlerUserUpdate(w http.ResponseWriter, r *http.Request) {
-	//update params
-	type UpdateUserParam struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
 	// auth
-	tokenString, err := auth.GetBearerToken(r.Header)
+	userID, _, err := app.authenticate(r)
 	if err != nil {
 		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenString, app.Config.JWTSecret)
+	param, err := httputil.DecodeJSON[UpdateUserRequest](w, r)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		httputil.RespondWithDecodeError(w, err)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	param := UpdateUserParam{}
-	err = decoder.Decode(&param)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
-	}
-
-	hashedPassword, err := auth.HashPassword(param.Password)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	updateUserParam := database.UpdateUserParams{
-		ID:             userID,
-		Email:          param.Email,
-		HashedPassword: hashedPassword,
-	}
-	updatedUser, err := app.Config.DB.UpdateUser(r.Context(), updateUserParam)
+	updatedUser, err := app.Users.Update(r.Context(), userID, param.Email, param.Password)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		respondUserError(w, r, err)
 		return
 	}
 
@@ -163,36 +148,15 @@ func (app *Application) HandlerUserUpdate(w http.ResponseWriter, r *http.Request
 
 func (app *Application) HandlerChirps(w http.ResponseWriter, r *http.Request) {
 	// authentication
-	tokenstring, err := auth.GetBearerToken(r.Header)
+	userID, err := app.authorize(r, auth.ScopeChirpsWrite)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		respondAuthError(w, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenstring, app.Config.JWTSecret)
+	params, err := httputil.DecodeJSON[CreateChirpRequest](w, r)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
-		return
-	}
-
-	const maxChirpLength = 200
-	type ChripParams struct {
-		Body string `json:"body"`
-	}
-
-	decoder := json.NewDecoder(r.Body)
-	params := ChripParams{}
-
-	err = decoder.Decode(&params)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
-		return
-	} else if len(params.Body) > maxChirpLength {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Chirp is too long")
-		return
-	}
-	if params.Body == "" {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Chirp body cannot be empty")
+		httputil.RespondWithDecodeError(w, err)
 		return
 	}
 	if userID == uuid.Nil {
@@ -200,96 +164,79 @@ func (app *Application) HandlerChirps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// if valid
-	createChirpParams := database.CreateChirpParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		Body:      params.Body,
-		UserID:    userID,
-	}
-	createdChirp, err := app.Config.DB.CreateChirp(r.Context(), createChirpParams)
+	createdChirp, err := app.Chirps.Create(r.Context(), userID, params.Body)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		httputil.RespondWithDBError(w, r, err)
+		return
 	}
 
 	httputil.RespondWithJSON(w, http.StatusCreated, createdChirp)
 }
 func (app *Application) HandlerGetChirps(w http.ResponseWriter, r *http.Request) {
-	var chirps []database.Chirp
-	var err error
-
-	authorIDStr := r.URL.Query().Get("author_id")
-	if authorIDStr == "" {
-		chirps, err = app.Config.DB.GetAllChirps(r.Context())
-		if err != nil {
-			httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-	} else {
+	if err := app.authorizeRead(r); err != nil {
+		respondAuthError(w, err)
+		return
+	}
+	opts := service.ListOptions{
+		NewestFirst: strings.ToLower(r.URL.Query().Get("sort")) == "desc",
+	}
+	if authorIDStr := r.URL.Query().Get("author_id"); authorIDStr != "" {
 		authorID, err := uuid.Parse(authorIDStr)
 		if err != nil {
 			httputil.RespondWithError(w, http.StatusBadRequest, "Author ID is invalid")
 			return
 		}
-		chirps, err = app.Config.DB.GetChirpsByAuthor(r.Context(), authorID)
+		opts.AuthorID = authorID
 	}
-	sortOrder := r.URL.Query().Get("sort")
 
-	if strings.ToLower(sortOrder) == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
-		})
+	chirps, err := app.Chirps.List(r.Context(), opts)
+	if err != nil {
+		httputil.RespondWithDBError(w, r, err)
+		return
 	}
 
-	httputil.RespondWithJSON(w, http.StatusOK, chirps)
+	httputil.RespondWithJSONETag(w, r, chirps)
 }
 func (app *Application) HandlerGetChirpByID(w http.ResponseWriter, r *http.Request) {
+	if err := app.authorizeRead(r); err != nil {
+		respondAuthError(w, err)
+		return
+	}
 	chirpIdPath := r.PathValue("chirpId")
 	chirpId, err := uuid.Parse(chirpIdPath)
 	if err != nil {
-		log.Fatalf("Error parsing chirpId: %v", err)
+		httputil.RespondWithError(w, http.StatusBadRequest, "Chirp ID is invalid")
 		return
 	}
 
-	chirp, err := app.Config.DB.GetChirpById(r.Context(), chirpId)
+	chirp, err := app.Chirps.Get(r.Context(), chirpId)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusNotFound, err.Error())
+		httputil.RespondWithDBError(w, r, err)
 		return
 	}
 
-	httputil.RespondWithJSON(w, http.StatusOK, chirp)
+	httputil.RespondWithJSONETag(w, r, chirp)
 }
 func (app *Application) HandlerDeleteChirpByID(w http.ResponseWriter, r *http.Request) {
 	//auth
-	tokenString, err := auth.GetBearerToken(r.Header)
+	userID, err := app.authorize(r, auth.ScopeChirpsWrite)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, app.Config.JWTSecret)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusForbidden, err.Error())
+		respondAuthError(w, err)
 		return
 	}
 	chirpIdPath := r.PathValue("chirpId")
 	chirpId, err := uuid.Parse(chirpIdPath)
 	if err != nil {
-		log.Fatalf("Error parsing chirpId: %v", err)
-		return
-	}
-	chirp, err := app.Config.DB.GetChirpById(r.Context(), chirpId)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusNotFound, err.Error())
+		httputil.RespondWithError(w, http.StatusBadRequest, "Chirp ID is invalid")
 		return
 	}
-	if chirp.UserID != userID {
+	err = app.Chirps.Delete(r.Context(), userID, chirpId)
+	if errors.Is(err, service.ErrForbidden) {
 		httputil.RespondWithError(w, http.StatusForbidden, "User ID inconsistent")
 		return
 	}
-
-	err = app.Config.DB.DeleteChirp(r.Context(), chirpId)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		httputil.RespondWithDBError(w, r, err)
 		return
 	}
 	httputil.RespondWithJSON(w, http.StatusNoContent, "")
@@ -297,72 +244,81 @@ func (app *Application) HandlerDeleteChirpByID(w http.ResponseWriter, r *http.Re
 }
 func (app *Application) HandlerLogin(w http.ResponseWriter, r *http.Request) {
 	// parse the request
-	type LoginParams struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
-	}
-
-	decoder := json.NewDecoder(r.Body)
-	params := LoginParams{}
-	err := decoder.Decode(&params)
+	params, err := httputil.DecodeJSON[LoginRequest](w, r)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
+		httputil.RespondWithDecodeError(w, err)
+		return
 	}
-	// get user by email
-	user, err := app.Config.DB.GetUserByEmail(r.Context(), params.Email)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, "User not found")
+	// refuse early while the account or client is locked out
+	clientIP := httputil.ClientIP(r)
+	if wait, ok := app.Config.LoginThrottle.Allow(params.Email, clientIP); !ok {
+		respondLockedOut(w, wait)
+		return
+	}
+	user, err := app.Users.Authenticate(r.Context(), params.Email, params.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		app.Config.LoginThrottle.RecordFailure(params.Email, clientIP)
+		httputil.RespondWithCode(w, http.StatusUnauthorized, codeInvalidCredentials, errInvalidCredentials)
 		return
 	}
-	// match password with the database
-	err = auth.CheckPassword(user.HashedPassword, params.Password)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusUnauthorized, "Password incorrect")
+		logging.FromContext(r.Context()).Error("loading user for login", "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not log in")
 		return
 	}
+	app.Config.LoginThrottle.RecordSuccess(params.Email)
 
-	// make JWT
-	accessTokenDuration := 1 * time.Hour
-	accessTokenString, err := auth.MakeJWT(user.ID, app.Config.JWTSecret, accessTokenDuration)
+	app.completeLogin(w, r, user)
+}
+
+// completeLogin is called once a user has proven their identity with a
+// first factor. Users with 2FA get a challenge, everyone else a session.
+func (app *Application) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	// second factor required: hand out a challenge instead of a session
+	enabled, err := app.TwoFactor.Enabled(r.Context(), user.ID)
 	if err != nil {
-		log.Printf("ERROR generating access token for user %s: %v", user.Email, err)
-		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not generate access token")
+		logging.FromContext(r.Context()).Error("loading two-factor settings", "user_id", user.ID, "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not check two-factor settings")
 		return
 	}
-	// make refresh token
-	refreshTokenString, err := auth.RefreshToken()
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	if enabled {
+		challengeToken, err := auth.MakeChallengeJWT(user.ID, app.Config.JWTSecret, twoFactorChallengeDuration)
+		if err != nil {
+			httputil.RespondWithError(w, http.StatusInternalServerError, "Could not generate challenge token")
+			return
+		}
+		httputil.RespondWithJSON(w, http.StatusOK, TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		})
 		return
 	}
 
-	// safe refresh token to database 60 days
-	refreshTokenExpiry := time.Now().UTC().Add(60 * 24 * time.Hour)
-	_, err = app.Config.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshTokenString,
-		UserID:    user.ID,
-		ExpiresAt: refreshTokenExpiry,
-	})
+	app.respondWithSession(w, r, user)
+}
+
+// respondWithSession issues an access/refresh token pair for an
+// authenticated user and writes it as a UserResponse.
+func (app *Application) respondWithSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	logging.AddRequestAttrs(r.Context(), slog.String("user_id", user.ID.String()))
+	session, err := app.Sessions.Issue(r.Context(), user)
 	if err != nil {
-		log.Printf("ERROR storing refresh token for user %s: %v", user.ID, err)
+		logging.FromContext(r.Context()).Error("starting session", "user_id", user.ID, "err", err)
 		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not store session")
 		return
 	}
 
-	// if match return OK status and json
 	response := UserResponse{
 		ID:           user.ID,
 		CreatedAt:    user.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    user.UpdatedAt.Format(time.RFC3339),
 		Email:        user.Email,
-		Token:        accessTokenString,
-		RefreshToken: refreshTokenString,
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
 		IsChirpyRed:  user.IsChirpyRed,
 	}
 
-	w.WriteHeader(http.StatusOK)
 	httputil.RespondWithJSON(w, http.StatusOK, response)
-
 }
 func (app *Application) HandlerRefreshToken(w http.ResponseWriter, r *http.Request) {
 
@@ -372,14 +328,11 @@ func (app *Application) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, err := app.Config.DB.GetUserForRefreshToken(r.Context(), refreshTokenString)
-	if err != nil {
+	newAccessTokenString, err := app.Sessions.Refresh(r.Context(), refreshTokenString)
+	if errors.Is(err, service.ErrInvalidSession) {
 		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired session")
 		return
 	}
-
-	newAccessTokenDuration := 1 * time.Hour
-	newAccessTokenString, err := auth.MakeJWT(user.ID, app.Config.JWTSecret, newAccessTokenDuration)
 	if err != nil {
 		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not refresh token")
 		return
@@ -389,13 +342,17 @@ func (app *Application) HandlerRefreshToken(w http.ResponseWriter, r *http.Reque
 	httputil.RespondWithJSON(w, http.StatusOK, response)
 }
 func (app *Application) HandlerRevokeToken(w http.ResponseWriter, r *http.Request) {
-	refreshTokenString, err := auth.GetBearerToken(r.Header)
+	tokenString, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		httputil.RespondWithError(w, http.StatusUnauthorized, "Missing or invalid token")
 		return
 	}
 
-	err = app.Config.DB.RevokeRefreshToken(r.Context(), refreshTokenString)
+	err = app.Sessions.Revoke(r.Context(), tokenString)
+	if errors.Is(err, service.ErrInvalidSession) {
+		httputil.RespondWithError(w, http.StatusBadRequest, "Token cannot be revoked")
+		return
+	}
 	if err != nil {
 		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not revoke session")
 		return
@@ -416,33 +373,47 @@ func (app *Application) HandlerPolkaWebhooks(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	type PolkaRequest struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
-	}
-	params := PolkaRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&params)
+	params, err := httputil.DecodeJSONLenient[PolkaWebhookRequest](w, r)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
+		httputil.RespondWithDecodeError(w, err)
+		return
 	}
 	if params.Event != "user.upgraded" {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	userID, err := uuid.Parse(params.Data.UserID)
+	err = app.Users.UpgradeToChirpyRed(r.Context(), params.userID)
 	if err != nil {
-		httputil.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
-		return
-	}
-	_, err = app.Config.DB.UpgradeUserToChirpyRed(r.Context(), userID)
-	if err != nil {
-		httputil.RespondWithError(w, http.StatusNotFound, "Could not upgrade user")
+		httputil.RespondWithDBError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // util
+func respondLockedOut(w http.ResponseWriter, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	httputil.RespondWithCode(w, http.StatusTooManyRequests, codeAccountLocked, "Too many failed login attempts, try again later")
+}
+
+// respondUserError reports a password policy violation as a field error and
+// anything else as a storage error, e.g. 409 for a taken email.
+func respondUserError(w http.ResponseWriter, r *http.Request, err error) error {
+	var code string
+	switch {
+	case errors.Is(err, auth.ErrPasswordTooShort):
+		code = "too_short"
+	case errors.Is(err, auth.ErrPasswordTooLong):
+		code = "too_long"
+	case errors.Is(err, auth.ErrPasswordCommon):
+		code = "too_common"
+	default:
+		return httputil.RespondWithDBError(w, r, err)
+	}
+	return httputil.RespondWithValidationErrors(w, []httputil.FieldError{{
+		Field:   "password",
+		Code:    code,
+		Message: err.Error(),
+	}})
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// HandlerReadyz reports whether the instance can serve traffic, i.e. its
+// dependencies are reachable. Unlike the liveness check in HandlerReadiness
+// it fails while the database is down, so load balancers stop routing to
+// the instance without restarting it.
+func (app *Application) HandlerReadyz(w http.ResponseWriter, r *http.Request) {
+	response := ReadinessResponse{
+		Status: "ok",
+		Checks: map[string]string{},
+	}
+
+	if app.Config.DBPinger != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+		defer cancel()
+		err := app.Config.DBPinger.PingContext(ctx)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("readiness check: database unreachable", "err", err)
+			response.Status = "unavailable"
+			response.Checks["database"] = "unreachable"
+		} else {
+			response.Checks["database"] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	httputil.RespondWithJSON(w, status, response)
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/mail"
+	"github.com/maevlava/chirpy/internal/service"
+)
+
+const magicLinkDuration = 15 * time.Minute
+
+// HandlerMagicLinkRequest emails a single-use login link. It answers 202 for
+// any well-formed email so the endpoint cannot be used to probe accounts;
+// unknown emails get an account when the link is confirmed.
+func (app *Application) HandlerMagicLinkRequest(w http.ResponseWriter, r *http.Request) {
+	if !app.Config.MagicLinks {
+		respondMagicLinksDisabled(w)
+		return
+	}
+	params, err := httputil.DecodeJSON[MagicLinkRequest](w, r)
+	if err != nil {
+		httputil.RespondWithDecodeError(w, err)
+		return
+	}
+	email := strings.TrimSpace(params.Email)
+
+	token, err := app.Users.CreateMagicLink(r.Context(), email, magicLinkDuration)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("storing magic link token", "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not send login link")
+		return
+	}
+
+	err = app.sendMagicLink(r.Context(), email, token)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("sending magic link", "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not send login link")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (app *Application) HandlerMagicLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	if !app.Config.MagicLinks {
+		respondMagicLinksDisabled(w)
+		return
+	}
+	params, err := httputil.DecodeJSON[MagicLinkConfirmRequest](w, r)
+	if err != nil {
+		httputil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	user, err := app.Users.UserForMagicLink(r.Context(), params.Token)
+	if errors.Is(err, service.ErrInvalidLoginLink) {
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired login link")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("resolving user for magic link", "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not log in")
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// respondMagicLinksDisabled answers like the OIDC handlers do when their
+// provider is not configured.
+func respondMagicLinksDisabled(w http.ResponseWriter) {
+	httputil.RespondWithError(w, http.StatusNotFound, "Login links are not configured")
+}
+
+func (app *Application) sendMagicLink(ctx context.Context, email, token string) error {
+	link, err := url.Parse(app.Config.MagicLinkURL)
+	if err != nil {
+		return fmt.Errorf("invalid magic link url: %v", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return app.Config.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your Chirpy login link",
+		Body: fmt.Sprintf("Click the link below to log in to Chirpy. It expires in %d minutes and works once.\n\n%s\n\n"+
+			"If you did not ask for this email you can ignore it.\n", int(magicLinkDuration/time.Minute), link),
+	})
+}
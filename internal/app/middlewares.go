@@ -1,19 +0,0 @@
-package app
-
-import (
-	"log"
-	"net/http"
-)
-
-func (app *Application) MiddlewareMetricsInc(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.Config.FileServerHits.Add(1)
-		next.ServeHTTP(w, r)
-	})
-}
-func MiddlewareLog(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/service"
+)
+
+const (
+	oidcStateCookie   = "chirpy_oidc_state"
+	oidcStateDuration = 10 * time.Minute
+	// the login may start and end under different API versions, e.g. an
+	// /api/v1 client with a callback registered on the unversioned path
+	oidcStateCookiePath = "/api"
+)
+
+// HandlerOIDCLogin starts the authorization code flow by redirecting to the
+// configured provider. State, nonce and PKCE verifier travel in a signed
+// cookie so the callback can be served by any replica.
+func (app *Application) HandlerOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider := app.Config.OIDCProvider
+	if provider == nil {
+		httputil.RespondWithError(w, http.StatusNotFound, "Login with provider is not configured")
+		return
+	}
+
+	authRequest, err := auth.NewOIDCAuthRequest()
+	if err != nil {
+		httputil.RespondWithInternalError(w, r, err)
+		return
+	}
+	redirectURL, err := provider.AuthCodeURL(r.Context(), authRequest)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("building oidc authorization url", "err", err)
+		httputil.RespondWithError(w, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+	stateToken, err := auth.MakeOIDCStateToken(authRequest, app.Config.JWTSecret, oidcStateDuration)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     oidcStateCookiePath,
+		MaxAge:   int(oidcStateDuration / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax so the cookie survives the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func (app *Application) HandlerOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider := app.Config.OIDCProvider
+	if provider == nil {
+		httputil.RespondWithError(w, http.StatusNotFound, "Login with provider is not configured")
+		return
+	}
+
+	// the state cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     oidcStateCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		// the value comes from the query string, so it is logged rather than
+		// echoed back to the client
+		logging.FromContext(r.Context()).Debug("identity provider refused login", "error", providerErr, "description", query.Get("error_description"))
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Login with provider was cancelled or failed")
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusBadRequest, "Missing login state")
+		return
+	}
+	authRequest, err := auth.ParseOIDCStateToken(cookie.Value, app.Config.JWTSecret)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusBadRequest, "Invalid or expired login state")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(authRequest.State), []byte(query.Get("state"))) != 1 {
+		httputil.RespondWithError(w, http.StatusBadRequest, "Login state mismatch")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		httputil.RespondWithError(w, http.StatusBadRequest, "Missing authorization code")
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), code, authRequest)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("exchanging oidc authorization code", "err", err)
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Could not verify identity")
+		return
+	}
+
+	user, err := app.Users.UserForIdentity(r.Context(), identity)
+	if errors.Is(err, service.ErrEmailTaken) {
+		httputil.RespondWithError(w, http.StatusConflict, "An account with this email already exists")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("resolving user for identity", "issuer", identity.Issuer, "subject", identity.Subject, "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not log in")
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+// Request bodies accepted by the API. Each implements httputil.Validator so
+// httputil.DecodeJSON rejects malformed input before a handler sees it.
+// Checks that need configuration, like the password policy, stay in the
+// handlers.
+
+const maxChirpLength = 200
+
+type CreateUserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (req *CreateUserRequest) Validate() []httputil.FieldError {
+	return append(validateEmail(req.Email), httputil.Required("password", req.Password)...)
+}
+
+type UpdateUserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (req *UpdateUserRequest) Validate() []httputil.FieldError {
+	return append(validateEmail(req.Email), httputil.Required("password", req.Password)...)
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (req *LoginRequest) Validate() []httputil.FieldError {
+	return append(httputil.Required("email", req.Email), httputil.Required("password", req.Password)...)
+}
+
+type CreateChirpRequest struct {
+	Body string `json:"body"`
+}
+
+func (req *CreateChirpRequest) Validate() []httputil.FieldError {
+	if len(req.Body) > maxChirpLength {
+		return []httputil.FieldError{{
+			Field:   "body",
+			Code:    "too_long",
+			Message: fmt.Sprintf("Chirp is too long, at most %d characters", maxChirpLength),
+		}}
+	}
+	return httputil.Required("body", req.Body)
+}
+
+type PolkaWebhookRequest struct {
+	Event string `json:"event"`
+	Data  struct {
+		UserID string `json:"user_id"`
+	} `json:"data"`
+
+	// userID is Data.UserID as parsed by Validate
+	userID uuid.UUID
+}
+
+func (req *PolkaWebhookRequest) Validate() []httputil.FieldError {
+	fieldErrors := httputil.Required("event", req.Event)
+	// only upgrades carry a user, other events are acknowledged and ignored
+	if req.Event == "user.upgraded" {
+		userID, err := uuid.Parse(req.Data.UserID)
+		if err != nil {
+			fieldErrors = append(fieldErrors, httputil.FieldError{
+				Field:   "data.user_id",
+				Code:    "invalid_uuid",
+				Message: "must be a UUID",
+			})
+		}
+		req.userID = userID
+	}
+	return fieldErrors
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (req *CreateAPIKeyRequest) Validate() []httputil.FieldError {
+	fieldErrors := httputil.Required("name", req.Name)
+	if len(req.Scopes) == 0 {
+		fieldErrors = append(fieldErrors, httputil.FieldError{
+			Field:   "scopes",
+			Code:    "required",
+			Message: "at least one scope is required",
+		})
+	}
+	return fieldErrors
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+func (req *MagicLinkRequest) Validate() []httputil.FieldError {
+	return validateEmail(req.Email)
+}
+
+type MagicLinkConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+func (req *MagicLinkConfirmRequest) Validate() []httputil.FieldError {
+	return httputil.Required("token", req.Token)
+}
+
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+func (req *TwoFactorConfirmRequest) Validate() []httputil.FieldError {
+	return httputil.Required("code", req.Code)
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (req *TwoFactorLoginRequest) Validate() []httputil.FieldError {
+	fieldErrors := httputil.Required("challenge_token", req.ChallengeToken)
+	if req.Code == "" && req.RecoveryCode == "" {
+		fieldErrors = append(fieldErrors, httputil.FieldError{
+			Field:   "code",
+			Code:    "required",
+			Message: "code or recovery_code is required",
+		})
+	}
+	return fieldErrors
+}
+
+func validateEmail(email string) []httputil.FieldError {
+	if fieldErrors := httputil.Required("email", email); fieldErrors != nil {
+		return fieldErrors
+	}
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 || strings.ContainsAny(email, " \t\r\n") {
+		return []httputil.FieldError{{Field: "email", Code: "invalid_email", Message: "must be an email address"}}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/service"
+)
+
+const (
+	twoFactorIssuer            = "Chirpy"
+	twoFactorChallengeDuration = 5 * time.Minute
+)
+
+type TwoFactorEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+func (app *Application) HandlerTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.authenticate(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user, err := app.Users.Get(r.Context(), userID)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	enrollment, err := app.TwoFactor.Enroll(r.Context(), userID)
+	if errors.Is(err, service.ErrTwoFactorEnabled) {
+		httputil.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("enrolling in two-factor authentication", "user_id", userID, "err", err)
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not enroll two-factor authentication")
+		return
+	}
+
+	httputil.RespondWithJSON(w, http.StatusCreated, TwoFactorEnrollResponse{
+		Secret:        enrollment.Secret,
+		OTPAuthURI:    auth.TOTPURI(enrollment.Secret, twoFactorIssuer, user.Email),
+		RecoveryCodes: enrollment.RecoveryCodes,
+	})
+}
+
+func (app *Application) HandlerTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.authenticate(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params, err := httputil.DecodeJSON[TwoFactorConfirmRequest](w, r)
+	if err != nil {
+		httputil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	err = app.TwoFactor.Confirm(r.Context(), userID, params.Code)
+	switch {
+	case errors.Is(err, service.ErrEnrollmentNotFound):
+		httputil.RespondWithError(w, http.StatusNotFound, "Two-factor enrollment not found")
+		return
+	case errors.Is(err, service.ErrTwoFactorEnabled):
+		httputil.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	case errors.Is(err, service.ErrInvalidCode):
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	case err != nil:
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not enable two-factor authentication")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *Application) HandlerLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	params, err := httputil.DecodeJSON[TwoFactorLoginRequest](w, r)
+	if err != nil {
+		httputil.RespondWithDecodeError(w, err)
+		return
+	}
+
+	// a challenge is spent by its first successful use and, like access
+	// tokens, dies with a password change
+	claims, err := auth.ParseChallengeJWT(params.ChallengeToken, app.Config.JWTSecret)
+	if err == nil {
+		err = auth.CheckNotRevoked(r.Context(), app.Config.TokenDenylist, claims)
+	}
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+	userID, err := auth.UserIDFromClaims(claims)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+
+	// codes are only six digits, so guesses are throttled like passwords
+	throttleKey := "2fa:" + userID.String()
+	clientIP := httputil.ClientIP(r)
+	if wait, ok := app.Config.LoginThrottle.Allow(throttleKey, clientIP); !ok {
+		respondLockedOut(w, wait)
+		return
+	}
+
+	err = app.TwoFactor.Verify(r.Context(), userID, params.Code, params.RecoveryCode)
+	switch {
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Two-factor authentication is not enabled")
+		return
+	case errors.Is(err, service.ErrInvalidCode):
+		app.Config.LoginThrottle.RecordFailure(throttleKey, clientIP)
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	case errors.Is(err, service.ErrInvalidRecoveryCode):
+		app.Config.LoginThrottle.RecordFailure(throttleKey, clientIP)
+		httputil.RespondWithError(w, http.StatusUnauthorized, "Invalid recovery code")
+		return
+	case err != nil:
+		httputil.RespondWithError(w, http.StatusInternalServerError, "Could not verify two-factor code")
+		return
+	}
+
+	app.Config.LoginThrottle.RecordSuccess(throttleKey)
+
+	err = app.Config.TokenDenylist.RevokeToken(r.Context(), claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		httputil.RespondWithInternalError(w, r, err)
+		return
+	}
+
+	user, err := app.Users.Get(r.Context(), userID)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	app.respondWithSession(w, r, user)
+}
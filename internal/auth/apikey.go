@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scopes limit what a personal API key may do. Access tokens from a login
+// implicitly carry every scope. Reading chirps is public, but a read sent
+// with a key needs chirps:read or chirps:write, so a read-only bot can hold
+// a key that cannot post.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+)
+
+var KnownScopes = []string{ScopeChirpsRead, ScopeChirpsWrite}
+
+const (
+	apiKeyPrefix      = "chirpy_"
+	apiKeySecretBytes = 32
+	// apiKeyDisplayLength is how much of a key is kept in clear text so
+	// users can tell their keys apart when listing them.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+)
+
+// GenerateAPIKey returns a new key and its displayable prefix. The key itself
+// is only ever shown once; store HashAPIKey(key) instead.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	randomBytes := make([]byte, apiKeySecretBytes)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	key = apiKeyPrefix + hex.EncodeToString(randomBytes)
+	return key, key[:apiKeyDisplayLength], nil
+}
+
+// HashAPIKey returns the lookup hash of a key. Keys are 256 bit random
+// values, so an unsalted SHA-256 is enough and allows direct lookup.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether the value looks like a personal API key, as
+// opposed to e.g. the Polka webhook key sent with the same header scheme.
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, apiKeyPrefix)
+}
+
+// ParseScopes validates requested scopes and returns them deduplicated in
+// canonical order.
+func ParseScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	var scopes []string
+	for _, known := range KnownScopes {
+		if slices.Contains(requested, known) {
+			scopes = append(scopes, known)
+		}
+	}
+	for _, scope := range requested {
+		if !slices.Contains(KnownScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return scopes, nil
+}
+
+// JoinScopes and SplitScopes convert between the scope list and the
+// space-separated form stored in api_keys.scopes (as in OAuth2 scope strings).
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func SplitScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
@@ -0,0 +1,46 @@
+package auth_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.IsAPIKey(key) {
+		t.Errorf("GenerateAPIKey() = %q, not recognised by IsAPIKey", key)
+	}
+	if !strings.HasPrefix(key, prefix) || prefix == key {
+		t.Errorf("GenerateAPIKey() prefix %q is not a strict prefix of the key", prefix)
+	}
+	if auth.HashAPIKey(key) == key || auth.HashAPIKey(key) != auth.HashAPIKey(key) {
+		t.Errorf("HashAPIKey() must be a deterministic digest")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	got, err := auth.ParseScopes([]string{auth.ScopeChirpsWrite, auth.ScopeChirpsRead, auth.ScopeChirpsWrite})
+	if err != nil {
+		t.Fatalf("ParseScopes() error = %v", err)
+	}
+	want := []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseScopes() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(auth.SplitScopes(auth.JoinScopes(got)), want) {
+		t.Errorf("SplitScopes(JoinScopes()) did not round trip")
+	}
+
+	if _, err := auth.ParseScopes([]string{"users:delete"}); err == nil {
+		t.Errorf("ParseScopes() with unknown scope error = nil")
+	}
+	if _, err := auth.ParseScopes(nil); err == nil {
+		t.Errorf("ParseScopes() with no scopes error = nil")
+	}
+}
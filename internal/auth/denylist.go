@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// TokenDenylist tracks access tokens that were revoked before they expired.
+// Single tokens are denied by jti, and all tokens of a user issued before a
+// revocation can be denied at once (e.g. after a password change).
+type TokenDenylist interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error
+	IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error)
+}
+
+// CheckNotRevoked returns ErrTokenRevoked when the denylist rejects the claims.
+func CheckNotRevoked(ctx context.Context, denylist TokenDenylist, claims *jwt.RegisteredClaims) error {
+	revoked, err := denylist.IsRevoked(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// MemoryDenylist is an in-process TokenDenylist. Entries are dropped once the
+// tokens they cover can no longer be valid, so memory stays bounded by the
+// number of revocations within one token lifetime.
+type MemoryDenylist struct {
+	mu       sync.Mutex
+	tokenTTL time.Duration
+	tokens   map[string]time.Time
+	users    map[uuid.UUID]time.Time
+	now      func() time.Time
+}
+
+// NewMemoryDenylist creates a denylist for tokens that live at most tokenTTL.
+func NewMemoryDenylist(tokenTTL time.Duration) *MemoryDenylist {
+	return &MemoryDenylist{
+		tokenTTL: tokenTTL,
+		tokens:   make(map[string]time.Time),
+		users:    make(map[uuid.UUID]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (d *MemoryDenylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep()
+	d.tokens[jti] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) RevokeUserTokens(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep()
+	cutoff := revocationCutoff(revokedAt)
+	if current, ok := d.users[userID]; !ok || cutoff.After(current) {
+		d.users[userID] = cutoff
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep()
+
+	if claims.ID != "" {
+		if _, ok := d.tokens[claims.ID]; ok {
+			return true, nil
+		}
+	}
+	userID, err := UserIDFromClaims(claims)
+	if err != nil {
+		return false, err
+	}
+	return issuedByCutoff(claims, d.users[userID]), nil
+}
+
+// sweep removes expired entries; callers must hold d.mu.
+func (d *MemoryDenylist) sweep() {
+	now := d.now()
+	for jti, expiresAt := range d.tokens {
+		if !expiresAt.After(now) {
+			delete(d.tokens, jti)
+		}
+	}
+	for userID, cutoff := range d.users {
+		if !cutoff.Add(d.tokenTTL).After(now) {
+			delete(d.users, userID)
+		}
+	}
+}
+
+// revocationCutoff returns the latest issue time a revocation at revokedAt
+// denies, at the precision of the iat claim. Every token minted before the
+// revocation is denied, while no login can complete within the same
+// microsecond after it.
+func revocationCutoff(revokedAt time.Time) time.Time {
+	return revokedAt.UTC().Truncate(jwt.TimePrecision)
+}
+
+// issuedByCutoff reports whether the token was issued at or before the
+// cutoff. Tokens without an iat claim are treated as issued before any cutoff.
+func issuedByCutoff(claims *jwt.RegisteredClaims, cutoff time.Time) bool {
+	if cutoff.IsZero() {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return !claims.IssuedAt.Time.After(cutoff)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+// StoreDenylist is a TokenDenylist backed by the revoked_access_tokens and
+// user_token_revocations tables of any database.Querier, Postgres or SQLite,
+// so it is shared by every server instance on the same database.
+type StoreDenylist struct {
+	db       database.Querier
+	tokenTTL time.Duration
+}
+
+// NewStoreDenylist creates a denylist for tokens that live at most tokenTTL.
+func NewStoreDenylist(db database.Querier, tokenTTL time.Duration) *StoreDenylist {
+	return &StoreDenylist{
+		db:       db,
+		tokenTTL: tokenTTL,
+	}
+}
+
+func (d *StoreDenylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := d.sweep(ctx); err != nil {
+		return err
+	}
+	return d.db.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       jti,
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
+
+func (d *StoreDenylist) RevokeUserTokens(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	if err := d.sweep(ctx); err != nil {
+		return err
+	}
+	return d.db.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{
+		UserID:        userID,
+		RevokedBefore: revocationCutoff(revokedAt),
+		ExpiresAt:     revokedAt.Add(d.tokenTTL).UTC(),
+	})
+}
+
+func (d *StoreDenylist) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := d.db.IsAccessTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	userID, err := UserIDFromClaims(claims)
+	if err != nil {
+		return false, err
+	}
+	cutoff, err := d.db.GetUserAccessTokensRevokedBefore(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedByCutoff(claims, cutoff), nil
+}
+
+// sweep deletes revocations whose tokens have expired. It runs on each
+// revocation so the tables stay small without a separate cleanup job.
+func (d *StoreDenylist) sweep(ctx context.Context) error {
+	if err := d.db.DeleteExpiredAccessTokenRevocations(ctx); err != nil {
+		return err
+	}
+	return d.db.DeleteExpiredUserTokenRevocations(ctx)
+}
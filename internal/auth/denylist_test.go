@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func TestMemoryDenylist(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	claimsFor := func(jti string, iat time.Time) *jwt.RegisteredClaims {
+		return &jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(iat),
+			ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour)),
+		}
+	}
+
+	t.Run("RevokedToken", func(t *testing.T) {
+		denylist := auth.NewMemoryDenylist(time.Hour)
+		revoked := claimsFor("revoked", issuedAt)
+		other := claimsFor("other", issuedAt)
+
+		if err := denylist.RevokeToken(ctx, revoked.ID, revoked.ExpiresAt.Time); err != nil {
+			t.Fatalf("RevokeToken() error = %v", err)
+		}
+		if err := auth.CheckNotRevoked(ctx, denylist, revoked); err != auth.ErrTokenRevoked {
+			t.Errorf("CheckNotRevoked() on revoked token error = %v, want %v", err, auth.ErrTokenRevoked)
+		}
+		if err := auth.CheckNotRevoked(ctx, denylist, other); err != nil {
+			t.Errorf("CheckNotRevoked() on other token error = %v, want nil", err)
+		}
+	})
+	t.Run("ExpiredEntryIsDropped", func(t *testing.T) {
+		denylist := auth.NewMemoryDenylist(time.Hour)
+		if err := denylist.RevokeToken(ctx, "expired", time.Now().Add(-time.Second)); err != nil {
+			t.Fatalf("RevokeToken() error = %v", err)
+		}
+		revoked, err := denylist.IsRevoked(ctx, claimsFor("expired", issuedAt))
+		if err != nil || revoked {
+			t.Errorf("IsRevoked() on expired entry = %v, %v, want false, nil", revoked, err)
+		}
+	})
+	t.Run("UserCutoff", func(t *testing.T) {
+		denylist := auth.NewMemoryDenylist(time.Hour)
+		revokedAt := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+		if err := denylist.RevokeUserTokens(ctx, userID, revokedAt); err != nil {
+			t.Fatalf("RevokeUserTokens() error = %v", err)
+		}
+		if revoked, _ := denylist.IsRevoked(ctx, claimsFor("old", issuedAt)); !revoked {
+			t.Errorf("IsRevoked() on token issued before revocation = false, want true")
+		}
+		// minted in the same second just before, e.g. by a concurrent login
+		if revoked, _ := denylist.IsRevoked(ctx, claimsFor("same-second", revokedAt.Add(-time.Millisecond))); !revoked {
+			t.Errorf("IsRevoked() on token issued just before revocation = false, want true")
+		}
+		if revoked, _ := denylist.IsRevoked(ctx, claimsFor("just-after", revokedAt.Add(10*time.Millisecond))); revoked {
+			t.Errorf("IsRevoked() on token issued just after revocation = true, want false")
+		}
+		if revoked, _ := denylist.IsRevoked(ctx, claimsFor("new", revokedAt.Add(time.Second))); revoked {
+			t.Errorf("IsRevoked() on token issued after revocation = true, want false")
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockoutPolicy controls how failed login attempts are punished. The first
+// FreeAttempts failures cost nothing, after that every failure locks the key
+// for BaseDelay doubled per extra failure, capped at MaxDelay. A key's
+// history is forgotten ResetAfter its last failure.
+type LockoutPolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	ResetAfter   time.Duration
+}
+
+var (
+	DefaultAccountLockout = LockoutPolicy{
+		FreeAttempts: 5,
+		BaseDelay:    1 * time.Second,
+		MaxDelay:     15 * time.Minute,
+		ResetAfter:   1 * time.Hour,
+	}
+	// IPs get more slack because many users can share one NAT address.
+	DefaultIPLockout = LockoutPolicy{
+		FreeAttempts: 20,
+		BaseDelay:    1 * time.Second,
+		MaxDelay:     15 * time.Minute,
+		ResetAfter:   1 * time.Hour,
+	}
+)
+
+type failureRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginThrottle tracks failed logins per account and per client IP and
+// decides whether a new attempt may be made.
+type LoginThrottle struct {
+	mu            sync.Mutex
+	accountPolicy LockoutPolicy
+	ipPolicy      LockoutPolicy
+	accounts      map[string]*failureRecord
+	ips           map[string]*failureRecord
+	// Now defaults to time.Now; tests replace it with a fixed clock.
+	Now func() time.Time
+}
+
+func NewLoginThrottle(accountPolicy, ipPolicy LockoutPolicy) *LoginThrottle {
+	return &LoginThrottle{
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+		accounts:      make(map[string]*failureRecord),
+		ips:           make(map[string]*failureRecord),
+		Now:           time.Now,
+	}
+}
+
+// Allow reports whether a login for account from ip may be attempted now.
+// When it may not, the returned duration is how long the caller has to wait.
+func (t *LoginThrottle) Allow(account, ip string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.Now()
+	t.sweep(now)
+
+	var wait time.Duration
+	for _, record := range []*failureRecord{t.accounts[normalizeAccount(account)], t.ips[ip]} {
+		if record != nil && record.lockedUntil.After(now) {
+			wait = max(wait, record.lockedUntil.Sub(now))
+		}
+	}
+	return wait, wait == 0
+}
+
+// RecordFailure counts a failed attempt against both the account and the ip.
+func (t *LoginThrottle) RecordFailure(account, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.Now()
+	t.sweep(now)
+
+	recordFailure(t.accounts, normalizeAccount(account), t.accountPolicy, now)
+	recordFailure(t.ips, ip, t.ipPolicy, now)
+}
+
+// RecordSuccess clears the account's history. The ip keeps its failures so a
+// client cannot reset its budget by logging into an account it owns.
+func (t *LoginThrottle) RecordSuccess(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.accounts, normalizeAccount(account))
+}
+
+// sweep forgets keys whose last failure is older than their policy allows;
+// callers must hold t.mu.
+func (t *LoginThrottle) sweep(now time.Time) {
+	sweepRecords(t.accounts, t.accountPolicy, now)
+	sweepRecords(t.ips, t.ipPolicy, now)
+}
+
+func recordFailure(records map[string]*failureRecord, key string, policy LockoutPolicy, now time.Time) {
+	if key == "" {
+		return
+	}
+	record, ok := records[key]
+	if !ok {
+		record = &failureRecord{}
+		records[key] = record
+	}
+	record.failures++
+	record.lastFailure = now
+
+	excess := record.failures - policy.FreeAttempts
+	if excess <= 0 {
+		return
+	}
+	delay := policy.BaseDelay
+	for i := 1; i < excess && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	record.lockedUntil = now.Add(min(delay, policy.MaxDelay))
+}
+
+func sweepRecords(records map[string]*failureRecord, policy LockoutPolicy, now time.Time) {
+	for key, record := range records {
+		if record.lockedUntil.After(now) {
+			continue
+		}
+		if now.Sub(record.lastFailure) >= policy.ResetAfter {
+			delete(records, key)
+		}
+	}
+}
+
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,68 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	policy := auth.LockoutPolicy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     4 * time.Second,
+		ResetAfter:   time.Minute,
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	newThrottle := func() *auth.LoginThrottle {
+		throttle := auth.NewLoginThrottle(policy, auth.DefaultIPLockout)
+		throttle.Now = func() time.Time { return now }
+		return throttle
+	}
+
+	t.Run("ExponentialBackoff", func(t *testing.T) {
+		throttle := newThrottle()
+		wantWaits := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+		for i, want := range wantWaits {
+			throttle.RecordFailure("Saul@BetterCall.com", "10.0.0.1")
+			wait, ok := throttle.Allow("saul@bettercall.com", "10.0.0.2")
+			if wait != want || ok != (want == 0) {
+				t.Errorf("after %d failures Allow() = %v, %v, want %v, %v", i+1, wait, ok, want, want == 0)
+			}
+		}
+	})
+	t.Run("SuccessResetsAccount", func(t *testing.T) {
+		throttle := newThrottle()
+		for i := 0; i < 3; i++ {
+			throttle.RecordFailure("saul@bettercall.com", "10.0.0.1")
+		}
+		throttle.RecordSuccess("saul@bettercall.com")
+		if _, ok := throttle.Allow("saul@bettercall.com", "10.0.0.2"); !ok {
+			t.Errorf("Allow() after success = false, want true")
+		}
+	})
+	t.Run("LockoutExpires", func(t *testing.T) {
+		throttle := newThrottle()
+		for i := 0; i < 3; i++ {
+			throttle.RecordFailure("saul@bettercall.com", "10.0.0.1")
+		}
+		now = now.Add(2 * time.Second)
+		if _, ok := throttle.Allow("saul@bettercall.com", "10.0.0.1"); !ok {
+			t.Errorf("Allow() after lockout expired = false, want true")
+		}
+	})
+	t.Run("PerIP", func(t *testing.T) {
+		throttle := auth.NewLoginThrottle(auth.DefaultAccountLockout, policy)
+		throttle.Now = func() time.Time { return now }
+		for i := 0; i < 3; i++ {
+			throttle.RecordFailure("user"+string(rune('a'+i))+"@example.com", "10.0.0.1")
+		}
+		if _, ok := throttle.Allow("fresh@example.com", "10.0.0.1"); ok {
+			t.Errorf("Allow() from locked ip = true, want false")
+		}
+		if _, ok := throttle.Allow("fresh@example.com", "10.0.0.2"); !ok {
+			t.Errorf("Allow() from other ip = false, want true")
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// MakeMagicLinkToken returns a single-use login token and the hash to store
+// for it. Only the hash is persisted, so a database leak yields no usable links.
+func MakeMagicLinkToken() (token string, hash string, err error) {
+	token, err = RefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashMagicLinkToken(token), nil
+}
+
+func HashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func TestMakeMagicLinkToken(t *testing.T) {
+	token, hash, err := auth.MakeMagicLinkToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == hash {
+		t.Errorf("MakeMagicLinkToken() hash equals the token")
+	}
+	if auth.HashMagicLinkToken(token) != hash {
+		t.Errorf("HashMagicLinkToken() does not match the hash from MakeMagicLinkToken()")
+	}
+
+	other, _, _ := auth.MakeMagicLinkToken()
+	if other == token {
+		t.Errorf("MakeMagicLinkToken() returned the same token twice")
+	}
+}
@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/auth/oidctest"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	ctx := context.Background()
+	fake := oidctest.NewProvider("chirpy-client", "chirpy-secret")
+	defer fake.Close()
+	fake.SetUser(oidctest.User{Subject: "user-123", Email: "saul@bettercall.com", EmailVerified: true})
+
+	provider := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:       fake.Issuer(),
+		ClientID:     "chirpy-client",
+		ClientSecret: "chirpy-secret",
+		RedirectURL:  "http://localhost:8080/api/login/oidc/callback",
+	}, fake.Client())
+
+	startLogin := func(t *testing.T) (auth.OIDCAuthRequest, string, string) {
+		t.Helper()
+		authRequest, err := auth.NewOIDCAuthRequest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		authURL, err := provider.AuthCodeURL(ctx, authRequest)
+		if err != nil {
+			t.Fatalf("AuthCodeURL() error = %v", err)
+		}
+		code, state, err := fake.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("Authorize() error = %v", err)
+		}
+		return authRequest, code, state
+	}
+
+	t.Run("AuthCodeURLUsesPKCE", func(t *testing.T) {
+		authRequest, _ := auth.NewOIDCAuthRequest()
+		authURL, err := provider.AuthCodeURL(ctx, authRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := mustParseURL(t, authURL).Query()
+		if query.Get("code_challenge") != auth.PKCEChallenge(authRequest.CodeVerifier) || query.Get("code_challenge_method") != "S256" {
+			t.Errorf("AuthCodeURL() missing S256 code challenge: %s", authURL)
+		}
+		if query.Get("code_challenge") == authRequest.CodeVerifier {
+			t.Errorf("AuthCodeURL() leaked the code verifier")
+		}
+	})
+	t.Run("CodeExchange", func(t *testing.T) {
+		authRequest, code, state := startLogin(t)
+		if state != authRequest.State {
+			t.Errorf("provider returned state %q, want %q", state, authRequest.State)
+		}
+		identity, err := provider.Exchange(ctx, code, authRequest)
+		if err != nil {
+			t.Fatalf("Exchange() error = %v", err)
+		}
+		want := auth.OIDCIdentity{Issuer: fake.Issuer(), Subject: "user-123", Email: "saul@bettercall.com", EmailVerified: true}
+		if *identity != want {
+			t.Errorf("Exchange() = %+v, want %+v", *identity, want)
+		}
+		if _, err := provider.Exchange(ctx, code, authRequest); err == nil {
+			t.Errorf("Exchange() accepted a replayed code")
+		}
+	})
+	t.Run("WrongVerifier", func(t *testing.T) {
+		authRequest, code, _ := startLogin(t)
+		authRequest.CodeVerifier = "not-the-verifier"
+		if _, err := provider.Exchange(ctx, code, authRequest); err == nil {
+			t.Errorf("Exchange() with wrong code verifier error = nil")
+		}
+	})
+	t.Run("NonceMismatch", func(t *testing.T) {
+		authRequest, code, _ := startLogin(t)
+		authRequest.Nonce = "another-nonce"
+		if _, err := provider.Exchange(ctx, code, authRequest); err == nil {
+			t.Errorf("Exchange() with wrong nonce error = nil")
+		}
+	})
+	t.Run("WrongAudience", func(t *testing.T) {
+		idToken, _ := fake.SignIDToken(jwt.MapClaims{
+			"iss":   fake.Issuer(),
+			"sub":   "user-123",
+			"aud":   "someone-else",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": "n",
+		})
+		if _, err := provider.VerifyIDToken(ctx, idToken, "n"); err == nil {
+			t.Errorf("VerifyIDToken() with foreign audience error = nil")
+		}
+	})
+}
+
+func TestOIDCStateToken(t *testing.T) {
+	secret := "rahasisaYangSangatKuat"
+	authRequest, _ := auth.NewOIDCAuthRequest()
+
+	stateToken, err := auth.MakeOIDCStateToken(authRequest, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := auth.ParseOIDCStateToken(stateToken, secret)
+	if err != nil || got != authRequest {
+		t.Errorf("ParseOIDCStateToken() = %+v, %v, want %+v", got, err, authRequest)
+	}
+	if _, err := auth.ValidateJWT(stateToken, secret); err == nil {
+		t.Errorf("ValidateJWT() accepted a state token as access token")
+	}
+	if _, err := auth.ParseOIDCStateToken(stateToken, "RahasiaYangSalah"); err == nil {
+		t.Errorf("ParseOIDCStateToken() with wrong secret error = nil")
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -1,21 +1,260 @@
 package auth
 
 import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// BcryptMaxPasswordLength is the number of password bytes bcrypt looks at;
+// the rest is ignored.
+const BcryptMaxPasswordLength = 72
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+	ErrPasswordCommon   = errors.New("password is too common")
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// PasswordPolicy is what HandlerUsers and HandlerUserUpdate require of new
+// passwords. MinLength counts characters, so a multibyte password is not held
+// to a higher bar; MaxLength counts bytes, since bcrypt ignores everything past
+// the 72nd byte.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	Banned    map[string]struct{}
+}
+
+// DefaultPasswordPolicy returns the policy with the built-in list of common
+// passwords banned.
+func DefaultPasswordPolicy() PasswordPolicy {
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			banned[strings.ToLower(word)] = struct{}{}
+		}
+	}
+	return PasswordPolicy{
+		MinLength: 8,
+		MaxLength: BcryptMaxPasswordLength,
+		Banned:    banned,
+	}
+}
+
+func (p PasswordPolicy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrPasswordTooLong, p.MaxLength)
+	}
+	if _, ok := p.Banned[strings.ToLower(password)]; ok {
+		return ErrPasswordCommon
+	}
+	return nil
+}
+
+// Argon2Params are the argon2id cost parameters encoded into every hash.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+// DefaultArgon2Params follow the RFC 9106 second recommended option.
+var DefaultArgon2Params = Argon2Params{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+	KeyLen:  32,
+	SaltLen: 16,
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies hashes of either algorithm, so the configuration can change
+// without locking out existing users.
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*PasswordHasher, error) {
+	switch algorithm {
+	case HashBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+		if argon2Params.Time == 0 || argon2Params.Memory == 0 || argon2Params.Threads == 0 {
+			return nil, fmt.Errorf("argon2id parameters must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+	return &PasswordHasher{
+		Algorithm:  algorithm,
+		BcryptCost: bcryptCost,
+		Argon2:     argon2Params,
+	}, nil
+}
+
+var defaultHasher = &PasswordHasher{
+	Algorithm:  HashBcrypt,
+	BcryptCost: bcrypt.DefaultCost,
+	Argon2:     DefaultArgon2Params,
+}
+
+// DefaultPasswordHasher returns a bcrypt hasher with the library default cost.
+func DefaultPasswordHasher() *PasswordHasher {
+	return defaultHasher
+}
+
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return defaultHasher.Hash(password)
+}
+func CheckPassword(hash, password string) error {
+	return defaultHasher.Check(hash, password)
+}
+
+// CheckPasswordDummy spends the same time as CheckPassword against a real
+// hash and always fails. Login uses it for unknown emails so response timing
+// does not reveal which accounts exist.
+func CheckPasswordDummy(password string) error {
+	return defaultHasher.CheckDummy(password)
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == HashArgon2id {
+		return h.hashArgon2id(password)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
-func CheckPassword(hash, password string) error {
+
+func (h *PasswordHasher) Check(hash, password string) error {
+	if strings.HasPrefix(hash, "$"+HashArgon2id+"$") {
+		return checkArgon2id(hash, password)
+	}
 	userPassword := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	if userPassword != nil {
 		return errors.New("password does not match")
 	}
 	return nil
 }
+
+// CheckDummy is CheckPassword against a throwaway hash made with the current
+// parameters, see CheckPasswordDummy.
+func (h *PasswordHasher) CheckDummy(password string) error {
+	h.dummyOnce.Do(func() {
+		h.dummyHash, _ = h.Hash("chirpy-dummy-password")
+	})
+	_ = h.Check(h.dummyHash, password)
+	return errors.New("password does not match")
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or weaker
+// parameters than the hasher currently uses.
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if h.Algorithm == HashArgon2id {
+		params, _, _, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.Time < h.Argon2.Time ||
+			params.Memory < h.Argon2.Memory ||
+			params.Threads != h.Argon2.Threads ||
+			params.KeyLen < h.Argon2.KeyLen
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < h.BcryptCost
+}
+
+func (h *PasswordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, h.Argon2.SaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+	p := h.Argon2
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+
+	// PHC string format, as produced by the reference implementation
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func checkArgon2id(hash, password string) error {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return errors.New("password does not match")
+	}
+	return nil
+}
+
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return Argon2Params{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	p := Argon2Params{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads)
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2id parameters: %v", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2id salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2id key: %v", err)
+	}
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+	return p, salt, key, nil
+}
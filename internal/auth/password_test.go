@@ -0,0 +1,88 @@
+package auth_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := auth.DefaultPasswordPolicy()
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{"Valid", "correct horse battery", nil},
+		{"Empty", "", auth.ErrPasswordTooShort},
+		{"TooShort", "abc12", auth.ErrPasswordTooShort},
+		{"TooLongForBcrypt", strings.Repeat("a", 73), auth.ErrPasswordTooLong},
+		// 9 bytes but 3 characters: the minimum counts characters
+		{"MultibyteTooShort", "日本語", auth.ErrPasswordTooShort},
+		{"Multibyte", "ひらがなカタカナ漢字", nil},
+		// 24 characters but 72 bytes: the maximum counts bytes
+		{"MultibyteAtBcryptLimit", strings.Repeat("語", 24), nil},
+		{"MultibyteTooLongForBcrypt", strings.Repeat("語", 25), auth.ErrPasswordTooLong},
+		{"Common", "Password123", auth.ErrPasswordCommon},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate(%q) error = %v, want %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	// cheap parameters keep the test fast
+	argon2Params := auth.Argon2Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+	bcryptHasher, err := auth.NewPasswordHasher(auth.HashBcrypt, bcrypt.MinCost, argon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2Hasher, err := auth.NewPasswordHasher(auth.HashArgon2id, bcrypt.MinCost, argon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hasher := range []*auth.PasswordHasher{bcryptHasher, argon2Hasher} {
+		t.Run(hasher.Algorithm, func(t *testing.T) {
+			hash, err := hasher.Hash("correct horse battery")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if err := hasher.Check(hash, "correct horse battery"); err != nil {
+				t.Errorf("Check() with right password error = %v", err)
+			}
+			if err := hasher.Check(hash, "wrong horse battery"); err == nil {
+				t.Errorf("Check() with wrong password error = nil")
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Errorf("NeedsRehash() on fresh hash = true")
+			}
+		})
+	}
+
+	t.Run("RehashAcrossAlgorithms", func(t *testing.T) {
+		bcryptHash, _ := bcryptHasher.Hash("correct horse battery")
+		if !argon2Hasher.NeedsRehash(bcryptHash) {
+			t.Errorf("argon2id NeedsRehash() on bcrypt hash = false")
+		}
+		// verification still works so the rehash can happen on login
+		if err := argon2Hasher.Check(bcryptHash, "correct horse battery"); err != nil {
+			t.Errorf("argon2id Check() on bcrypt hash error = %v", err)
+		}
+	})
+	t.Run("RehashOnHigherCost", func(t *testing.T) {
+		oldHash, _ := bcryptHasher.Hash("correct horse battery")
+		stronger, _ := auth.NewPasswordHasher(auth.HashBcrypt, bcrypt.MinCost+1, argon2Params)
+		if !stronger.NeedsRehash(oldHash) {
+			t.Errorf("NeedsRehash() with higher cost = false")
+		}
+	})
+}
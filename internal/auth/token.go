@@ -12,6 +12,19 @@ import (
 	"time"
 )
 
+// ChallengeAudience marks the short-lived tokens handed out between the
+// password and second factor steps of a login. They are rejected wherever
+// an access token is expected.
+const ChallengeAudience = "chirpy-2fa"
+
+// Tokens carry microsecond issue times, the precision of a Postgres
+// TIMESTAMP, so revoking a user's tokens tells those minted just before the
+// revocation from a login just after it. Other JWT libraries read
+// fractional NumericDates as well.
+func init() {
+	jwt.TimePrecision = time.Microsecond
+}
+
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
 	signingMethod := jwt.SigningMethodHS256
 	claims := jwt.RegisteredClaims{
@@ -19,6 +32,7 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 		Subject:   userID.String(),
+		ID:        uuid.NewString(),
 	}
 
 	token := jwt.NewWithClaims(signingMethod, claims)
@@ -31,6 +45,16 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return UserIDFromClaims(claims)
+}
+
+// ParseJWT verifies the token signature and expiry and returns its claims,
+// so callers can inspect the jti and issue time for revocation checks.
+func ParseJWT(tokenString, tokenSecret string) (*jwt.RegisteredClaims, error) {
 	claims := jwt.RegisteredClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
@@ -43,13 +67,58 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	})
 
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return uuid.Nil, fmt.Errorf("token is invalid")
+		return nil, fmt.Errorf("token is invalid")
+	}
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("token is not an access token")
+	}
+
+	return &claims, nil
+}
+
+// MakeChallengeJWT issues a token proving the password step of a login
+// succeeded. It can only be exchanged via ParseChallengeJWT.
+func MakeChallengeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{ChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		Subject:   userID.String(),
+		ID:        uuid.NewString(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(tokenSecret))
+}
+
+func ValidateChallengeJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims, err := ParseChallengeJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
 	}
+	return UserIDFromClaims(claims)
+}
+
+// ParseChallengeJWT verifies a challenge token and returns its claims, so
+// the second step can check it against the denylist and spend its jti.
+func ParseChallengeJWT(tokenString, tokenSecret string) (*jwt.RegisteredClaims, error) {
+	claims := jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(ChallengeAudience))
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
 
+func UserIDFromClaims(claims *jwt.RegisteredClaims) (uuid.UUID, error) {
 	subject, err := claims.GetSubject()
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("could not get subject from token claims: %v", err)
@@ -52,3 +52,26 @@ func TestValidateJWT(t *testing.T) {
 		}
 	})
 }
+
+func TestMakeJWTSetsUniqueID(t *testing.T) {
+	secret := "rahasisaYangSangatKuat"
+	userId := uuid.New()
+
+	first, _ := auth.MakeJWT(userId, secret, time.Hour)
+	second, _ := auth.MakeJWT(userId, secret, time.Hour)
+
+	firstClaims, err := auth.ParseJWT(first, secret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	secondClaims, err := auth.ParseJWT(second, secret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if firstClaims.ID == "" {
+		t.Errorf("MakeJWT() token has empty jti")
+	}
+	if firstClaims.ID == secondClaims.ID {
+		t.Errorf("MakeJWT() issued two tokens with the same jti %q", firstClaims.ID)
+	}
+}
@@ -0,0 +1,106 @@
+package auth_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+// rfc6238Secret is the SHA1 test key from RFC 6238 appendix B
+// ("12345678901234567890") in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := auth.TOTPCode(rfc6238Secret, auth.TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode() at %d error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("CurrentStep", func(t *testing.T) {
+		step, ok := auth.ValidateTOTP(rfc6238Secret, "050471", now)
+		if !ok || step != auth.TOTPStep(now) {
+			t.Errorf("ValidateTOTP() = %d, %v, want %d, true", step, ok, auth.TOTPStep(now))
+		}
+	})
+	t.Run("PreviousStepWithinSkew", func(t *testing.T) {
+		if _, ok := auth.ValidateTOTP(rfc6238Secret, "050471", now.Add(auth.TOTPPeriod)); !ok {
+			t.Errorf("ValidateTOTP() one step late = false, want true")
+		}
+	})
+	t.Run("OutsideSkew", func(t *testing.T) {
+		if _, ok := auth.ValidateTOTP(rfc6238Secret, "050471", now.Add(3*auth.TOTPPeriod)); ok {
+			t.Errorf("ValidateTOTP() three steps late = true, want false")
+		}
+	})
+	t.Run("WrongCode", func(t *testing.T) {
+		if _, ok := auth.ValidateTOTP(rfc6238Secret, "000000", now); ok {
+			t.Errorf("ValidateTOTP() with wrong code = true, want false")
+		}
+	})
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(auth.TOTPURI(secret, "Chirpy", "saul@bettercall.com"))
+	if err != nil {
+		t.Fatalf("TOTPURI() is not a valid URL: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("TOTPURI() = %s, want otpauth://totp/...", uri)
+	}
+	if uri.Query().Get("secret") != secret {
+		t.Errorf("TOTPURI() secret = %q, want %q", uri.Query().Get("secret"), secret)
+	}
+	if !strings.HasSuffix(uri.Path, "Chirpy:saul@bettercall.com") {
+		t.Errorf("TOTPURI() label = %q", uri.Path)
+	}
+}
+
+func TestChallengeJWTIsNotAnAccessToken(t *testing.T) {
+	secret := "rahasisaYangSangatKuat"
+	userId := uuid.New()
+
+	challenge, err := auth.MakeChallengeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ValidateJWT(challenge, secret); err == nil {
+		t.Errorf("ValidateJWT() accepted a challenge token")
+	}
+	gotId, err := auth.ValidateChallengeJWT(challenge, secret)
+	if err != nil || gotId != userId {
+		t.Errorf("ValidateChallengeJWT() = %v, %v, want %v, nil", gotId, err, userId)
+	}
+
+	access, _ := auth.MakeJWT(userId, secret, time.Minute)
+	if _, err := auth.ValidateChallengeJWT(access, secret); err == nil {
+		t.Errorf("ValidateChallengeJWT() accepted an access token")
+	}
+}
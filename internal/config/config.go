@@ -1,29 +1,139 @@
 package config
 
 import (
+	"context"
+	"github.com/maevlava/chirpy/internal/auth"
 	"github.com/maevlava/chirpy/internal/database"
-	"log"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/mail"
+	"github.com/maevlava/chirpy/internal/metrics"
+	"github.com/maevlava/chirpy/internal/ratelimit"
+	"log/slog"
 	"os"
-	"sync/atomic"
+	"time"
 )
 
+// Pinger reports whether a backing service is reachable; *sql.DB
+// implements it.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// ApiConfig holds the runtime dependencies of the application, built from
+// a validated Config.
 type ApiConfig struct {
-	FileServerHits atomic.Int32
-	WebStaticDir   string
-	DB             *database.Queries
-	JWTSecret      string
-	PolkaApiKey    string
+	Platform        string
+	Server          ServerConfig
+	Database        DatabaseConfig
+	CORS            CORSConfig
+	API             APIConfig
+	WebStaticDir    string
+	DB              database.Store
+	DBPinger        Pinger
+	JWTSecret       string
+	PolkaApiKey     string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	TokenDenylist   auth.TokenDenylist
+	LoginThrottle   *auth.LoginThrottle
+	PasswordPolicy  auth.PasswordPolicy
+	PasswordHasher  *auth.PasswordHasher
+	OIDCProvider    *auth.OIDCProvider
+	Mailer          mail.Mailer
+	MagicLinkURL    string
+	MagicLinks      bool
+	Metrics         *metrics.Metrics
+	Logger          *slog.Logger
+	RateLimits      map[string]ratelimit.Policy
+	RateLimitStore  ratelimit.Store
 }
 
-func Load() *ApiConfig {
-	secret := os.Getenv("JWT_SECRET")
-	PolkaAPIKey := os.Getenv("POLKA_KEY")
-	if secret == "" || PolkaAPIKey == "" {
-		log.Fatal("env missing value")
+// Load reads the config file named by CHIRPY_CONFIG, if set, applies
+// environment overrides and builds the ApiConfig.
+func Load() (*ApiConfig, error) {
+	cfg, err := LoadConfig(os.Getenv("CHIRPY_CONFIG"))
+	if err != nil {
+		return nil, err
 	}
-	return &ApiConfig{
-		WebStaticDir: "./web/static",
-		JWTSecret:    secret,
-		PolkaApiKey:  PolkaAPIKey,
+	return New(cfg)
+}
+
+// LoadDatabase reads the database section of the config file named by
+// CHIRPY_CONFIG, if set, with its environment overrides.
+func LoadDatabase() (*DatabaseConfig, error) {
+	return LoadDatabaseConfig(os.Getenv("CHIRPY_CONFIG"))
+}
+
+// New builds the runtime ApiConfig from a validated Config. The database
+// and stores that depend on it are wired separately by the caller.
+func New(cfg *Config) (*ApiConfig, error) {
+	policy := auth.DefaultPasswordPolicy()
+	policy.MinLength = cfg.Auth.PasswordMinLength
+	policy.MaxLength = cfg.Auth.PasswordMaxLength
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := auth.NewPasswordHasher(cfg.Auth.PasswordHash, cfg.Auth.BcryptCost, auth.DefaultArgon2Params)
+	if err != nil {
+		return nil, err
+	}
+
+	// login with provider is optional and only enabled when an issuer is set
+	var oidcProvider *auth.OIDCProvider
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider = auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+		}, nil)
+	}
+
+	// without SMTP, mail only reaches the log, which is fine for reading
+	// login links on a laptop but hands accounts to anyone reading the logs
+	// elsewhere; MagicLinks turns the login links off there instead
+	var mailer mail.Mailer = mail.LogMailer{IncludeBody: cfg.Platform == PlatformDev}
+	magicLinks := cfg.Platform == PlatformDev
+	if cfg.SMTP.Addr != "" {
+		magicLinks = true
+		mailer = mail.SMTPMailer{
+			Addr:     cfg.SMTP.Addr,
+			From:     cfg.SMTP.From,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}
 	}
+
+	rateLimits := map[string]ratelimit.Policy{}
+	if cfg.RateLimit.Enabled {
+		for route, policy := range cfg.RateLimit.Routes {
+			if policy.Requests > 0 {
+				rateLimits[route] = ratelimit.Policy{Requests: policy.Requests, Period: policy.Period}
+			}
+		}
+	}
+
+	return &ApiConfig{
+		Platform:        cfg.Platform,
+		Server:          cfg.Server,
+		Database:        cfg.Database,
+		CORS:            cfg.CORS,
+		API:             cfg.API,
+		WebStaticDir:    cfg.WebStaticDir,
+		JWTSecret:       cfg.Auth.JWTSecret,
+		PolkaApiKey:     cfg.Auth.PolkaKey,
+		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		PasswordPolicy:  policy,
+		PasswordHasher:  hasher,
+		OIDCProvider:    oidcProvider,
+		Mailer:          mailer,
+		MagicLinkURL:    cfg.MagicLinkURL,
+		MagicLinks:      magicLinks,
+		Logger:          logger,
+		RateLimits:      rateLimits,
+	}, nil
 }
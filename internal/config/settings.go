@@ -0,0 +1,580 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/logging"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Platforms Chirpy knows about. Destructive admin endpoints such as
+// POST /admin/reset are only enabled on PlatformDev.
+const (
+	PlatformDev        = "dev"
+	PlatformStaging    = "staging"
+	PlatformProduction = "production"
+)
+
+// Config is the typed configuration of the server. It is read from an
+// optional YAML file, then overridden from the environment, so deployments
+// can keep secrets in env vars and everything else in the file.
+type Config struct {
+	Platform     string          `yaml:"platform"`
+	WebStaticDir string          `yaml:"web_static_dir"`
+	MagicLinkURL string          `yaml:"magic_link_url"`
+	Server       ServerConfig    `yaml:"server"`
+	Database     DatabaseConfig  `yaml:"database"`
+	Auth         AuthConfig      `yaml:"auth"`
+	OIDC         OIDCConfig      `yaml:"oidc"`
+	SMTP         SMTPConfig      `yaml:"smtp"`
+	Log          LogConfig       `yaml:"log"`
+	RateLimit    RateLimitConfig `yaml:"rate_limit"`
+	CORS         CORSConfig      `yaml:"cors"`
+	API          APIConfig       `yaml:"api"`
+}
+
+// ServerConfig controls the HTTP listener. Timeouts guard against slow
+// clients holding connections open; ShutdownTimeout bounds how long
+// in-flight requests may drain on SIGINT/SIGTERM.
+//
+// Setting TLSCertFile and TLSKeyFile serves HTTPS on Port; HTTPRedirectPort
+// then optionally serves plain HTTP redirecting to it. HSTSMaxAge is how
+// long browsers remember to only use HTTPS, zero to not send HSTS.
+//
+// TrustedProxies lists the addresses or CIDR ranges of the reverse proxies
+// in front of the server. Only requests from them may name the client in
+// Forwarded or X-Forwarded-For; otherwise every client behind a proxy
+// would share its address for rate limits and lockouts.
+type ServerConfig struct {
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	TLSCertFile       string        `yaml:"tls_cert_file"`
+	TLSKeyFile        string        `yaml:"tls_key_file"`
+	HTTPRedirectPort  int           `yaml:"http_redirect_port"`
+	HSTSMaxAge        time.Duration `yaml:"hsts_max_age"`
+	TrustedProxies    []string      `yaml:"trusted_proxies"`
+}
+
+// TLS reports whether the server terminates TLS itself.
+func (s ServerConfig) TLS() bool {
+	return s.TLSCertFile != ""
+}
+
+// TrustedProxyPrefixes returns the parsed TrustedProxies of a validated
+// ServerConfig, single addresses as one-address prefixes.
+func (s ServerConfig) TrustedProxyPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, proxy := range s.TrustedProxies {
+		if prefix, ok := parseProxy(proxy); ok {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+func parseProxy(proxy string) (netip.Prefix, bool) {
+	if addr, err := netip.ParseAddr(proxy); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	prefix, err := netip.ParsePrefix(proxy)
+	return prefix.Masked(), err == nil
+}
+
+// DatabaseConfig holds the driver, connection string and *sql.DB pool
+// limits. Driver is "postgres" or "sqlite"; for SQLite, URL is the database
+// file path. Zero MaxOpenConns means unlimited, as in database/sql.
+// ConnectTimeout bounds how long startup keeps retrying an unreachable
+// database. AutoMigrate applies pending migrations on boot.
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver"`
+	URL             string        `yaml:"url"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type AuthConfig struct {
+	JWTSecret         string        `yaml:"jwt_secret"`
+	PolkaKey          string        `yaml:"polka_key"`
+	AccessTokenTTL    time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL   time.Duration `yaml:"refresh_token_ttl"`
+	PasswordMinLength int           `yaml:"password_min_length"`
+	PasswordMaxLength int           `yaml:"password_max_length"`
+	PasswordHash      string        `yaml:"password_hash"`
+	BcryptCost        int           `yaml:"bcrypt_cost"`
+}
+
+// OIDCConfig enables login with an external provider when Issuer is set.
+type OIDCConfig struct {
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+// SMTPConfig enables sending mail when Addr is set; otherwise mail is logged.
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// LogConfig controls the structured logger. Level is debug, info, warn or
+// error; Format is json, for log collectors, or text, for reading locally.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// RateLimitConfig throttles clients, identified by user ID when they send
+// a valid access token and by IP otherwise. Routes maps a pattern such as
+// "POST /api/chirps" to its policy, which also covers the route in every
+// API version, e.g. "POST /api/v1/chirps"; routes not listed are not
+// limited, and a policy of 0 requests lifts the default limit of a route.
+type RateLimitConfig struct {
+	Enabled bool                       `yaml:"enabled"`
+	Routes  map[string]RateLimitPolicy `yaml:"routes"`
+}
+
+// RateLimitPolicy allows bursts of Requests refilled evenly over Period.
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+}
+
+// CORSConfig lets browser apps on other origins call the API. Origins are
+// exact scheme://host[:port] values, or "*" for any origin without
+// credentials; with none listed only same-origin pages can call the API.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// APIConfig schedules the retirement of the unversioned /api/ paths, which
+// alias /api/v1/ for clients written before versioning. DeprecatedAt and
+// SunsetAt are dates such as 2026-10-19, sent to those clients in the
+// Deprecation and Sunset headers; an empty SunsetAt sends no Sunset.
+type APIConfig struct {
+	DeprecatedAt string `yaml:"deprecated_at"`
+	SunsetAt     string `yaml:"sunset_at"`
+}
+
+// dateLayout is the format of the dates in APIConfig.
+const dateLayout = time.DateOnly
+
+// Deprecation returns the parsed dates of a validated APIConfig, with a
+// zero sunset when none is set.
+func (a APIConfig) Deprecation() (deprecatedAt, sunsetAt time.Time) {
+	deprecatedAt, _ = time.Parse(dateLayout, a.DeprecatedAt)
+	if a.SunsetAt != "" {
+		sunsetAt, _ = time.Parse(dateLayout, a.SunsetAt)
+	}
+	return deprecatedAt, sunsetAt
+}
+
+// Defaults returns the configuration used for anything neither the file
+// nor the environment sets.
+func Defaults() Config {
+	policy := auth.DefaultPasswordPolicy()
+	return Config{
+		Platform:     PlatformProduction,
+		WebStaticDir: "./web/static",
+		MagicLinkURL: "http://localhost:8080/app/login/magic",
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+			HSTSMaxAge:        365 * 24 * time.Hour,
+		},
+		Database: DatabaseConfig{
+			Driver:          database.DriverPostgres,
+			ConnectTimeout:  30 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:    1 * time.Hour,
+			RefreshTokenTTL:   60 * 24 * time.Hour,
+			PasswordMinLength: policy.MinLength,
+			PasswordMaxLength: policy.MaxLength,
+			PasswordHash:      auth.HashBcrypt,
+			BcryptCost:        bcrypt.DefaultCost,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Routes: map[string]RateLimitPolicy{
+				"POST /api/login":       {Requests: 10, Period: time.Minute},
+				"POST /api/login/2fa":   {Requests: 10, Period: time.Minute},
+				"POST /api/login/magic": {Requests: 5, Period: time.Minute},
+				"POST /api/users":       {Requests: 5, Period: time.Minute},
+				"POST /api/chirps":      {Requests: 30, Period: time.Minute},
+			},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID", "If-None-Match"},
+			ExposedHeaders: []string{"X-Request-ID", "ETag", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Deprecation", "Sunset", "Link"},
+			MaxAge:         10 * time.Minute,
+		},
+		API: APIConfig{
+			DeprecatedAt: "2026-10-19",
+			SunsetAt:     "2027-04-19",
+		},
+	}
+}
+
+// LoadConfig builds the configuration from Defaults, the YAML file at path
+// (skipped when path is empty) and the environment, then validates it. All
+// problems are reported at once, joined into a single error.
+func LoadConfig(path string) (*Config, error) {
+	cfg := Defaults()
+	if path != "" {
+		err := cfg.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	errs := cfg.applyEnv(os.LookupEnv)
+	errs = append(errs, cfg.Validate())
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadDatabaseConfig is LoadConfig for commands that only talk to the
+// database, such as migrations: it reads the same file and environment but
+// applies and validates only the database section, so secrets the server
+// needs, like the JWT secret, may be absent.
+func LoadDatabaseConfig(path string) (*DatabaseConfig, error) {
+	cfg := Defaults()
+	if path != "" {
+		err := cfg.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	env := envLoader{lookup: os.LookupEnv}
+	cfg.Database.applyEnv(&env)
+	err := errors.Join(append(env.errs, cfg.Database.Validate())...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return &cfg.Database, nil
+}
+
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	// a typo in a key should fail loudly, not silently fall back to a default
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields from environment variables. The names predate
+// the config file and are kept for existing deployments.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) []error {
+	env := envLoader{lookup: lookup}
+
+	env.string("PLATFORM", &c.Platform)
+	env.string("WEB_STATIC_DIR", &c.WebStaticDir)
+	env.string("MAGIC_LINK_URL", &c.MagicLinkURL)
+
+	env.int("PORT", &c.Server.Port)
+	env.duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.duration("HTTP_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	env.string("TLS_CERT_FILE", &c.Server.TLSCertFile)
+	env.string("TLS_KEY_FILE", &c.Server.TLSKeyFile)
+	env.int("HTTP_REDIRECT_PORT", &c.Server.HTTPRedirectPort)
+	env.duration("HSTS_MAX_AGE", &c.Server.HSTSMaxAge)
+	env.list("TRUSTED_PROXIES", &c.Server.TrustedProxies)
+
+	c.Database.applyEnv(&env)
+
+	env.string("JWT_SECRET", &c.Auth.JWTSecret)
+	env.string("POLKA_KEY", &c.Auth.PolkaKey)
+	env.duration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	env.duration("REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL)
+	env.int("PASSWORD_MIN_LENGTH", &c.Auth.PasswordMinLength)
+	env.int("PASSWORD_MAX_LENGTH", &c.Auth.PasswordMaxLength)
+	env.string("PASSWORD_HASH", &c.Auth.PasswordHash)
+	env.int("BCRYPT_COST", &c.Auth.BcryptCost)
+
+	env.string("OIDC_ISSUER", &c.OIDC.Issuer)
+	env.string("OIDC_CLIENT_ID", &c.OIDC.ClientID)
+	env.string("OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret)
+	env.string("OIDC_REDIRECT_URL", &c.OIDC.RedirectURL)
+
+	env.string("SMTP_ADDR", &c.SMTP.Addr)
+	env.string("SMTP_FROM", &c.SMTP.From)
+	env.string("SMTP_USERNAME", &c.SMTP.Username)
+	env.string("SMTP_PASSWORD", &c.SMTP.Password)
+
+	env.string("LOG_LEVEL", &c.Log.Level)
+	env.string("LOG_FORMAT", &c.Log.Format)
+
+	env.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+
+	env.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+	env.bool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	env.duration("CORS_MAX_AGE", &c.CORS.MaxAge)
+
+	env.string("API_DEPRECATED_AT", &c.API.DeprecatedAt)
+	env.string("API_SUNSET_AT", &c.API.SunsetAt)
+
+	return env.errs
+}
+
+func (d *DatabaseConfig) applyEnv(env *envLoader) {
+	env.string("DB_DRIVER", &d.Driver)
+	env.string("DB_URL", &d.URL)
+	env.duration("DB_CONNECT_TIMEOUT", &d.ConnectTimeout)
+	env.bool("DB_AUTO_MIGRATE", &d.AutoMigrate)
+	env.int("DB_MAX_OPEN_CONNS", &d.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &d.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &d.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &d.ConnMaxIdleTime)
+}
+
+// checker collects the problems found by Validate.
+type checker []error
+
+func (c *checker) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		*c = append(*c, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+}
+
+// Validate checks the whole configuration and returns every problem found.
+func (c *Config) Validate() error {
+	var errs checker
+	check := errs.check
+
+	platforms := []string{PlatformDev, PlatformStaging, PlatformProduction}
+	check(slices.Contains(platforms, c.Platform), "platform", "must be one of %v, got %q", platforms, c.Platform)
+	check(c.WebStaticDir != "", "web_static_dir", "is required")
+	check(isURL(c.MagicLinkURL), "magic_link_url", "must be an absolute URL")
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "must be between 1 and 65535")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout", "must not be negative")
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""),
+		"server.tls_key_file", "must be set together with server.tls_cert_file")
+	if c.Server.HTTPRedirectPort != 0 {
+		check(c.Server.TLS(), "server.http_redirect_port", "requires server.tls_cert_file")
+		check(c.Server.HTTPRedirectPort > 0 && c.Server.HTTPRedirectPort <= 65535 && c.Server.HTTPRedirectPort != c.Server.Port,
+			"server.http_redirect_port", "must be between 1 and 65535 and differ from server.port")
+	}
+	check(c.Server.HSTSMaxAge >= 0, "server.hsts_max_age", "must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, ok := parseProxy(proxy)
+		check(ok, "server.trusted_proxies", "must be IP addresses or CIDR ranges, got %q", proxy)
+	}
+
+	errs = append(errs, c.Database.check()...)
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret", "is required")
+	check(c.Auth.PolkaKey != "", "auth.polka_key", "is required")
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl", "must be positive")
+	check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL, "auth.refresh_token_ttl", "must be longer than access_token_ttl")
+	check(c.Auth.PasswordMinLength > 0, "auth.password_min_length", "must be positive")
+	check(c.Auth.PasswordMaxLength >= c.Auth.PasswordMinLength, "auth.password_max_length", "must not be below password_min_length")
+	check(c.Auth.PasswordHash == auth.HashBcrypt || c.Auth.PasswordHash == auth.HashArgon2id,
+		"auth.password_hash", "must be %q or %q", auth.HashBcrypt, auth.HashArgon2id)
+	check(c.Auth.PasswordHash != auth.HashBcrypt || c.Auth.PasswordMaxLength <= auth.BcryptMaxPasswordLength,
+		"auth.password_max_length", "must not exceed %d with bcrypt, which ignores the rest", auth.BcryptMaxPasswordLength)
+	check(c.Auth.BcryptCost >= bcrypt.MinCost && c.Auth.BcryptCost <= bcrypt.MaxCost,
+		"auth.bcrypt_cost", "must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	if c.OIDC.Issuer != "" {
+		check(isURL(c.OIDC.Issuer), "oidc.issuer", "must be an absolute URL")
+		check(c.OIDC.ClientID != "", "oidc.client_id", "is required when oidc.issuer is set")
+		check(isURL(c.OIDC.RedirectURL), "oidc.redirect_url", "must be an absolute URL when oidc.issuer is set")
+	}
+	if c.SMTP.Addr != "" {
+		check(c.SMTP.From != "", "smtp.from", "is required when smtp.addr is set")
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == logging.FormatJSON || c.Log.Format == logging.FormatText,
+		"log.format", "must be %q or %q", logging.FormatJSON, logging.FormatText)
+
+	for route, policy := range c.RateLimit.Routes {
+		field := fmt.Sprintf("rate_limit.routes[%q]", route)
+		method, path, ok := strings.Cut(route, " ")
+		check(ok && method != "" && strings.HasPrefix(path, "/"), field, "must be a method and a path such as \"POST /api/chirps\"")
+		check(policy.Requests >= 0, field+".requests", "must not be negative")
+		check(policy.Requests == 0 || policy.Period > 0, field+".period", "must be positive")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || isOrigin(origin), "cors.allowed_origins", "must be \"*\" or a scheme://host[:port] origin, got %q", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+		"cors.allow_credentials", "cannot be combined with the \"*\" origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age", "must not be negative")
+
+	deprecatedAt, err := time.Parse(dateLayout, c.API.DeprecatedAt)
+	check(err == nil, "api.deprecated_at", "must be a date like 2026-10-19, got %q", c.API.DeprecatedAt)
+	if c.API.SunsetAt != "" {
+		sunsetAt, err := time.Parse(dateLayout, c.API.SunsetAt)
+		check(err == nil, "api.sunset_at", "must be a date like 2027-04-19, got %q", c.API.SunsetAt)
+		check(err != nil || sunsetAt.After(deprecatedAt), "api.sunset_at", "must be after api.deprecated_at")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the database section alone.
+func (d DatabaseConfig) Validate() error {
+	return errors.Join(d.check()...)
+}
+
+func (d DatabaseConfig) check() checker {
+	var errs checker
+	check := errs.check
+	check(d.Driver == database.DriverPostgres || d.Driver == database.DriverSQLite,
+		"database.driver", "must be %q or %q", database.DriverPostgres, database.DriverSQLite)
+	check(d.URL != "", "database.url", "is required")
+	// startup pings within it, so zero would fail before the first ping
+	check(d.ConnectTimeout > 0, "database.connect_timeout", "must be positive")
+	check(d.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	check(d.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(d.MaxOpenConns == 0 || d.MaxIdleConns <= d.MaxOpenConns,
+		"database.max_idle_conns", "must not exceed max_open_conns")
+	check(d.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+	check(d.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative")
+	return errs
+}
+
+// isOrigin reports whether value is a bare origin as browsers send it in
+// the Origin header, without path, query or trailing slash.
+func isOrigin(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// envLoader parses environment overrides, collecting errors instead of
+// stopping at the first bad value.
+type envLoader struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (e *envLoader) string(key string, target *string) {
+	if value, ok := e.lookup(key); ok && value != "" {
+		*target = value
+	}
+}
+
+// list splits a comma separated value, e.g. "https://a.com,https://b.com".
+func (e *envLoader) list(key string, target *[]string) {
+	value, ok := e.lookup(key)
+	if !ok || value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (e *envLoader) int(key string, target *int) {
+	value, ok := e.lookup(key)
+	if !ok || value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("env %s: must be an integer, got %q", key, value))
+		return
+	}
+	*target = n
+}
+
+func (e *envLoader) bool(key string, target *bool) {
+	value, ok := e.lookup(key)
+	if !ok || value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("env %s: must be true or false, got %q", key, value))
+		return
+	}
+	*target = b
+}
+
+func (e *envLoader) duration(key string, target *time.Duration) {
+	value, ok := e.lookup(key)
+	if !ok || value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("env %s: must be a duration like 10s, got %q", key, value))
+		return
+	}
+	*target = d
+}
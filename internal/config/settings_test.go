@@ -0,0 +1,334 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/config"
+)
+
+// clearEnv unsets every variable LoadConfig reads so the tests do not
+// depend on the developer's shell.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"PLATFORM", "WEB_STATIC_DIR", "MAGIC_LINK_URL", "PORT", "DB_DRIVER", "DB_URL",
+		"DB_CONNECT_TIMEOUT", "DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "JWT_SECRET", "POLKA_KEY",
+		"ACCESS_TOKEN_TTL", "REFRESH_TOKEN_TTL", "PASSWORD_MIN_LENGTH", "PASSWORD_MAX_LENGTH",
+		"PASSWORD_HASH", "BCRYPT_COST",
+		"OIDC_ISSUER", "SMTP_ADDR", "HTTP_SHUTDOWN_TIMEOUT", "LOG_LEVEL", "LOG_FORMAT",
+		"RATE_LIMIT_ENABLED", "CORS_ALLOWED_ORIGINS", "CORS_ALLOW_CREDENTIALS",
+		"TLS_CERT_FILE", "TLS_KEY_FILE", "HTTP_REDIRECT_PORT", "HSTS_MAX_AGE", "TRUSTED_PROXIES",
+		"API_DEPRECATED_AT", "API_SUNSET_AT",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chirpy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileAndEnv(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+platform: dev
+server:
+  port: 9000
+  shutdown_timeout: 5s
+database:
+  url: postgres://file
+  max_open_conns: 10
+  max_idle_conns: 5
+auth:
+  jwt_secret: from-file
+  polka_key: polka
+  access_token_ttl: 15m
+`)
+	t.Setenv("JWT_SECRET", "from-env")
+	t.Setenv("PORT", "9100")
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Auth.JWTSecret != "from-env" || cfg.Server.Port != 9100 {
+		t.Errorf("env did not override file: secret %q port %d", cfg.Auth.JWTSecret, cfg.Server.Port)
+	}
+	if cfg.Platform != config.PlatformDev || cfg.Database.MaxOpenConns != 10 || cfg.Auth.AccessTokenTTL != 15*time.Minute {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Server.ShutdownTimeout != 5*time.Second || cfg.Server.ReadTimeout != config.Defaults().Server.ReadTimeout {
+		t.Errorf("durations = %s/%s", cfg.Server.ShutdownTimeout, cfg.Server.ReadTimeout)
+	}
+}
+
+func TestLoadConfigAggregatesErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PLATFORM", "laptop")
+	t.Setenv("PORT", "eighty")
+	t.Setenv("ACCESS_TOKEN_TTL", "-1m")
+	t.Setenv("DB_DRIVER", "mysql")
+	t.Setenv("LOG_LEVEL", "loud")
+
+	_, err := config.LoadConfig("")
+	if err == nil {
+		t.Fatal("LoadConfig() succeeded with an invalid environment")
+	}
+	for _, want := range []string{"env PORT", "platform", "database.driver", "database.url", "auth.jwt_secret", "auth.polka_key", "auth.access_token_ttl", "log.level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfigRateLimitRoutes(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+
+	path := writeConfig(t, `
+rate_limit:
+  routes:
+    "POST /api/chirps": {requests: 100, period: 1h}
+`)
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	routes := cfg.RateLimit.Routes
+	if got := routes["POST /api/chirps"]; got.Requests != 100 || got.Period != time.Hour {
+		t.Errorf("file policy not applied: %+v", got)
+	}
+	if _, ok := routes["POST /api/login"]; !ok {
+		t.Error("default policies were replaced instead of merged")
+	}
+
+	path = writeConfig(t, `
+rate_limit:
+  routes:
+    "/api/chirps": {requests: -1, period: 0s}
+`)
+	_, err = config.LoadConfig(path)
+	for _, want := range []string{`rate_limit.routes["/api/chirps"]:`, ".requests", ".period"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadConfigCORS(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://chirpy.example.com, http://localhost:5173")
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := []string{"https://chirpy.example.com", "http://localhost:5173"}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+		t.Errorf("allowed origins = %q, want %q", cfg.CORS.AllowedOrigins, want)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*,https://chirpy.example.com/app")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	_, err = config.LoadConfig("")
+	for _, want := range []string{"cors.allowed_origins", `"https://chirpy.example.com/app"`, "cors.allow_credentials"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadConfigTLS(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("TLS_CERT_FILE", "/etc/chirpy/cert.pem")
+	t.Setenv("HTTP_REDIRECT_PORT", "8080")
+
+	_, err := config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "server.tls_key_file") {
+		t.Errorf("LoadConfig() error = %v, want a missing key file", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "server.http_redirect_port") {
+		t.Errorf("LoadConfig() error = %v, want the redirect port to clash with server.port", err)
+	}
+
+	t.Setenv("TLS_KEY_FILE", "/etc/chirpy/key.pem")
+	t.Setenv("PORT", "8443")
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !cfg.Server.TLS() || cfg.Server.HTTPRedirectPort != 8080 {
+		t.Errorf("server = %+v, want TLS with a redirect from 8080", cfg.Server)
+	}
+
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+	_, err = config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "server.http_redirect_port") {
+		t.Errorf("LoadConfig() error = %v, want a redirect port without TLS refused", err)
+	}
+}
+
+func TestLoadConfigPasswordMaxLength(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("PASSWORD_MAX_LENGTH", "128")
+
+	_, err := config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "auth.password_max_length") {
+		t.Errorf("LoadConfig() error = %v, want a max length past bcrypt's 72 bytes refused", err)
+	}
+
+	t.Setenv("PASSWORD_HASH", "argon2id")
+	if _, err := config.LoadConfig(""); err != nil {
+		t.Errorf("LoadConfig() with argon2id error = %v", err)
+	}
+}
+
+func TestLoadConfigTrustedProxies(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 2001:db8::1")
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	got := cfg.Server.TrustedProxyPrefixes()
+	if len(got) != 2 || got[0].String() != "10.0.0.0/8" || got[1].String() != "2001:db8::1/128" {
+		t.Errorf("trusted proxies = %v, want 10.0.0.0/8 and 2001:db8::1/128", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "load-balancer")
+	_, err = config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "server.trusted_proxies") {
+		t.Errorf("LoadConfig() error = %v, want a host name refused", err)
+	}
+}
+
+func TestLoadConfigAPIDeprecation(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("API_DEPRECATED_AT", "2027-01-01")
+	t.Setenv("API_SUNSET_AT", "")
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	deprecatedAt, sunsetAt := cfg.API.Deprecation()
+	if !deprecatedAt.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) || sunsetAt.IsZero() {
+		t.Errorf("deprecation = %s, sunset %s, want the default sunset kept", deprecatedAt, sunsetAt)
+	}
+
+	path := writeConfig(t, `
+api:
+  deprecated_at: 2027-05-01
+  sunset_at: ""
+`)
+	cfg, err = config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if _, sunsetAt := cfg.API.Deprecation(); !sunsetAt.IsZero() {
+		t.Errorf("sunset = %s, want none", sunsetAt)
+	}
+
+	t.Setenv("API_DEPRECATED_AT", "next year")
+	t.Setenv("API_SUNSET_AT", "soon")
+	_, err = config.LoadConfig("")
+	for _, want := range []string{"api.deprecated_at", "api.sunset_at"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig() error = %v, want it to mention %s", err, want)
+		}
+	}
+
+	t.Setenv("API_DEPRECATED_AT", "2027-01-01")
+	t.Setenv("API_SUNSET_AT", "2026-01-01")
+	_, err = config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "must be after api.deprecated_at") {
+		t.Errorf("LoadConfig() error = %v, want a sunset before the deprecation refused", err)
+	}
+}
+
+func TestLoadConfigConnectTimeout(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("DB_CONNECT_TIMEOUT", "0s")
+
+	_, err := config.LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "database.connect_timeout") {
+		t.Errorf("LoadConfig() error = %v, want a zero connect timeout refused", err)
+	}
+}
+
+func TestLoadDatabaseConfig(t *testing.T) {
+	clearEnv(t)
+	// neither the server's secrets nor its other settings are looked at
+	t.Setenv("PORT", "not-a-port")
+	path := writeConfig(t, "database:\n  driver: sqlite\n  url: chirpy.db\n")
+
+	cfg, err := config.LoadDatabaseConfig(path)
+	if err != nil {
+		t.Fatalf("LoadDatabaseConfig() error = %v", err)
+	}
+	if cfg.Driver != "sqlite" || cfg.URL != "chirpy.db" {
+		t.Errorf("database = %+v, want sqlite at chirpy.db", cfg)
+	}
+
+	t.Setenv("DB_URL", "")
+	t.Setenv("DB_DRIVER", "mysql")
+	_, err = config.LoadDatabaseConfig("")
+	for _, want := range []string{"database.driver", "database.url"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadDatabaseConfig() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, "server:\n  prot: 8080\n")
+
+	_, err := config.LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Fatalf("LoadConfig() error = %v, want unknown field error", err)
+	}
+}
+
+func TestExampleConfigIsValid(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("POLKA_KEY", "polka")
+
+	_, err := config.LoadConfig("../../chirpy.example.yaml")
+	if err != nil {
+		t.Fatalf("example config does not load: %v", err)
+	}
+}
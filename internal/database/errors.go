@@ -0,0 +1,72 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// ConstraintErrors classifies the constraint violations of one driver's
+// errors. Drivers other than Postgres register theirs with
+// RegisterConstraintErrors, so this package does not import them and
+// builds without cgo.
+type ConstraintErrors struct {
+	IsUniqueViolation     func(err error) bool
+	IsForeignKeyViolation func(err error) bool
+}
+
+var (
+	constraintErrorsMu sync.RWMutex
+	constraintErrors   []ConstraintErrors
+)
+
+// RegisterConstraintErrors adds a driver's classifier, typically from the
+// init function of the package implementing the driver's store.
+func RegisterConstraintErrors(c ConstraintErrors) {
+	constraintErrorsMu.Lock()
+	defer constraintErrorsMu.Unlock()
+	constraintErrors = append(constraintErrors, c)
+}
+
+func anyConstraintError(err error, is func(ConstraintErrors) func(error) bool) bool {
+	constraintErrorsMu.RLock()
+	defer constraintErrorsMu.RUnlock()
+	for _, c := range constraintErrors {
+		if fn := is(c); fn != nil && fn(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err means a :one query matched no row.
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// IsUniqueViolation reports whether err was caused by a UNIQUE or PRIMARY KEY
+// constraint, e.g. registering an email that is already taken.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pqUniqueViolation
+	}
+	return anyConstraintError(err, func(c ConstraintErrors) func(error) bool { return c.IsUniqueViolation })
+}
+
+// IsForeignKeyViolation reports whether err references a row that does not exist.
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pqForeignKeyViolation
+	}
+	return anyConstraintError(err, func(c ConstraintErrors) func(error) bool { return c.IsForeignKeyViolation })
+}
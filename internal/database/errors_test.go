@@ -0,0 +1,25 @@
+package database_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestBuildsWithoutCgo keeps the cgo SQLite driver out of Postgres-only
+// builds: only the sqlite package may depend on cgo, and it must still
+// compile without it.
+func TestBuildsWithoutCgo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the whole module")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cmd := exec.Command(goTool, "build", "github.com/maevlava/chirpy/...")
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CGO_ENABLED=0 go build: %v\n%s", err, out)
+	}
+}
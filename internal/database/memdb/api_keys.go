@@ -0,0 +1,82 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	defer q.lock()()
+	if _, ok := q.tables.apiKeys[arg.ID]; ok {
+		return database.ApiKey{}, uniqueError("api_keys_pkey")
+	}
+	for _, key := range q.tables.apiKeys {
+		if key.KeyHash == arg.KeyHash {
+			return database.ApiKey{}, uniqueError("api_keys_key_hash_key")
+		}
+	}
+	if err := q.userExists("api_keys", arg.UserID); err != nil {
+		return database.ApiKey{}, err
+	}
+	key := database.ApiKey{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Prefix:    arg.Prefix,
+		KeyHash:   arg.KeyHash,
+		Scopes:    arg.Scopes,
+		CreatedAt: q.timestamp(),
+	}
+	q.tables.apiKeys[key.ID] = key
+	return key, nil
+}
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	defer q.lock()()
+	for _, key := range q.tables.apiKeys {
+		if key.KeyHash == keyHash && !key.RevokedAt.Valid {
+			return key, nil
+		}
+	}
+	return database.ApiKey{}, sql.ErrNoRows
+}
+
+func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	defer q.lock()()
+	var keys []database.ApiKey
+	for _, key := range q.tables.apiKeys {
+		if key.UserID == userID && !key.RevokedAt.Valid {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b database.ApiKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg database.RevokeAPIKeyParams) (int64, error) {
+	defer q.lock()()
+	key, ok := q.tables.apiKeys[arg.ID]
+	if !ok || key.UserID != arg.UserID || key.RevokedAt.Valid {
+		return 0, nil
+	}
+	key.RevokedAt = sql.NullTime{Time: q.timestamp(), Valid: true}
+	q.tables.apiKeys[key.ID] = key
+	return 1, nil
+}
+
+func (q *Queries) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	defer q.lock()()
+	key, ok := q.tables.apiKeys[id]
+	if !ok {
+		return nil
+	}
+	key.LastUsedAt = sql.NullTime{Time: q.timestamp(), Valid: true}
+	q.tables.apiKeys[id] = key
+	return nil
+}
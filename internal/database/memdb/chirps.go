@@ -0,0 +1,73 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	defer q.lock()()
+	if _, ok := q.tables.chirps[arg.ID]; ok {
+		return database.Chirp{}, uniqueError("chirp_pkey")
+	}
+	if err := q.userExists("chirp", arg.UserID); err != nil {
+		return database.Chirp{}, err
+	}
+	chirp := database.Chirp{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+	}
+	q.tables.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	defer q.lock()()
+	delete(q.tables.chirps, id)
+	return nil
+}
+
+func (q *Queries) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	defer q.lock()()
+	return q.chirpsWhere(func(database.Chirp) bool { return true }), nil
+}
+
+func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	defer q.lock()()
+	chirp, ok := q.tables.chirps[id]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	defer q.lock()()
+	return q.chirpsWhere(func(chirp database.Chirp) bool { return chirp.UserID == userID }), nil
+}
+
+// chirpsWhere returns the matching chirps ORDER BY created_at, breaking
+// ties by ID so results are stable.
+func (q *Queries) chirpsWhere(match func(database.Chirp) bool) []database.Chirp {
+	var chirps []database.Chirp
+	for _, chirp := range q.tables.chirps {
+		if match(chirp) {
+			chirps = append(chirps, chirp)
+		}
+	}
+	slices.SortFunc(chirps, func(a, b database.Chirp) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return chirps
+}
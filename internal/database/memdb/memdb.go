@@ -0,0 +1,184 @@
+// Package memdb provides an in-memory database.Store for tests. It mirrors
+// the Postgres schema closely enough for handlers to behave as they do in
+// production: unique and foreign key constraints fail with the same
+// *pq.Error codes, deleting a user cascades to the rows that reference it,
+// and expiring rows honour the store's clock.
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+// Postgres error codes returned for constraint violations, so callers can
+// keep using database.IsUniqueViolation and friends.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type recoveryCodeKey struct {
+	userID   uuid.UUID
+	codeHash string
+}
+
+// tables holds one map per table, keyed by primary key.
+type tables struct {
+	users               map[uuid.UUID]database.User
+	chirps              map[uuid.UUID]database.Chirp
+	refreshTokens       map[string]database.RefreshToken
+	revokedAccessTokens map[string]database.RevokedAccessToken
+	userRevocations     map[uuid.UUID]database.UserTokenRevocation
+	userTOTP            map[uuid.UUID]database.UserTotp
+	recoveryCodes       map[recoveryCodeKey]database.TotpRecoveryCode
+	apiKeys             map[uuid.UUID]database.ApiKey
+	identities          map[uuid.UUID]database.UserIdentity
+	magicLinkTokens     map[string]database.MagicLinkToken
+}
+
+func newTables() *tables {
+	return &tables{
+		users:               map[uuid.UUID]database.User{},
+		chirps:              map[uuid.UUID]database.Chirp{},
+		refreshTokens:       map[string]database.RefreshToken{},
+		revokedAccessTokens: map[string]database.RevokedAccessToken{},
+		userRevocations:     map[uuid.UUID]database.UserTokenRevocation{},
+		userTOTP:            map[uuid.UUID]database.UserTotp{},
+		recoveryCodes:       map[recoveryCodeKey]database.TotpRecoveryCode{},
+		apiKeys:             map[uuid.UUID]database.ApiKey{},
+		identities:          map[uuid.UUID]database.UserIdentity{},
+		magicLinkTokens:     map[string]database.MagicLinkToken{},
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		users:               cloneMap(t.users),
+		chirps:              cloneMap(t.chirps),
+		refreshTokens:       cloneMap(t.refreshTokens),
+		revokedAccessTokens: cloneMap(t.revokedAccessTokens),
+		userRevocations:     cloneMap(t.userRevocations),
+		userTOTP:            cloneMap(t.userTOTP),
+		recoveryCodes:       cloneMap(t.recoveryCodes),
+		apiKeys:             cloneMap(t.apiKeys),
+		identities:          cloneMap(t.identities),
+		magicLinkTokens:     cloneMap(t.magicLinkTokens),
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// Queries implements database.Querier over a set of tables. Outside a
+// transaction every call takes the store lock; inside one the lock is
+// already held for the whole transaction.
+type Queries struct {
+	mu     sync.Locker
+	tables *tables
+	now    func() time.Time
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func (q *Queries) lock() func() {
+	q.mu.Lock()
+	return q.mu.Unlock
+}
+
+// timestamp returns the current time at the precision of a Postgres
+// TIMESTAMP column.
+func (q *Queries) timestamp() time.Time {
+	return q.now().UTC().Truncate(time.Microsecond)
+}
+
+// Store is an in-memory database.Store. The zero value is not usable, use New.
+type Store struct {
+	Queries
+	mu    sync.Mutex
+	clock func() time.Time
+}
+
+var _ database.Store = (*Store)(nil)
+
+func New() *Store {
+	s := &Store{clock: time.Now}
+	s.Queries = Queries{
+		mu:     &s.mu,
+		tables: newTables(),
+		now:    s.Now,
+	}
+	return s
+}
+
+// Now returns the store's current time, what NOW() would return in SQL.
+func (s *Store) Now() time.Time {
+	return s.clock()
+}
+
+// SetClock replaces the store's clock, e.g. to move past token expiry.
+// It must not be called concurrently with queries.
+func (s *Store) SetClock(now func() time.Time) {
+	s.clock = now
+}
+
+// WithTx runs fn against a copy of the tables while holding the store lock
+// and swaps the copy in when fn succeeds, so transactions are serializable
+// and a failed one leaves no trace. fn must only use the Querier it is
+// given; calling the Store itself from fn deadlocks.
+func (s *Store) WithTx(ctx context.Context, fn func(database.Querier) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Queries{
+		mu:     noLock{},
+		tables: s.tables.clone(),
+		now:    s.Now,
+	}
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+	s.tables = tx.tables
+	return nil
+}
+
+type noLock struct{}
+
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
+
+func uniqueError(constraint string) error {
+	return &pq.Error{
+		Code:       uniqueViolation,
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyError(table, constraint string) error {
+	return &pq.Error{
+		Code:       foreignKeyViolation,
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// userExists checks the foreign key from table to users.
+func (q *Queries) userExists(table string, userID uuid.UUID) error {
+	if _, ok := q.tables.users[userID]; !ok {
+		return foreignKeyError(table, table+"_user_id_fkey")
+	}
+	return nil
+}
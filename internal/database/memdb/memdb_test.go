@@ -0,0 +1,160 @@
+package memdb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/database/memdb"
+)
+
+func createUser(t *testing.T, store *memdb.Store, email string) database.User {
+	t.Helper()
+	now := time.Now()
+	user, err := store.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Email:     email,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) error = %v", email, err)
+	}
+	return user
+}
+
+func TestUniqueEmail(t *testing.T) {
+	ctx := context.Background()
+	store := memdb.New()
+	walter := createUser(t, store, "walter@breakingbad.com")
+	jesse := createUser(t, store, "jesse@breakingbad.com")
+
+	_, err := store.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Email: walter.Email})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("CreateUser() with taken email error = %v, want unique violation", err)
+	}
+
+	_, err = store.UpdateUser(ctx, database.UpdateUserParams{ID: jesse.ID, Email: walter.Email})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("UpdateUser() to taken email error = %v, want unique violation", err)
+	}
+	_, err = store.UpdateUser(ctx, database.UpdateUserParams{ID: jesse.ID, Email: jesse.Email})
+	if err != nil {
+		t.Errorf("UpdateUser() keeping own email error = %v", err)
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	_, err := memdb.New().CreateChirp(context.Background(), database.CreateChirpParams{
+		ID:     uuid.New(),
+		Body:   "orphan",
+		UserID: uuid.New(),
+	})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("CreateChirp() for unknown user error = %v, want foreign key violation", err)
+	}
+}
+
+func TestDeleteAllUsersCascades(t *testing.T) {
+	ctx := context.Background()
+	store := memdb.New()
+	user := createUser(t, store, "walter@breakingbad.com")
+
+	chirp, err := store.CreateChirp(ctx, database.CreateChirpParams{ID: uuid.New(), Body: "Say my name", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateAPIKey(ctx, database.CreateAPIKeyParams{ID: uuid.New(), UserID: user.ID, KeyHash: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.UpsertUserTOTP(ctx, database.UpsertUserTOTPParams{UserID: user.ID, Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.DeleteAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetChirpById(ctx, chirp.ID); !database.IsNotFound(err) {
+		t.Errorf("GetChirpById() after delete error = %v, want not found", err)
+	}
+	if _, err := store.GetAPIKeyByHash(ctx, "hash"); !database.IsNotFound(err) {
+		t.Errorf("GetAPIKeyByHash() after delete error = %v, want not found", err)
+	}
+	if _, err := store.GetUserTOTP(ctx, user.ID); !database.IsNotFound(err) {
+		t.Errorf("GetUserTOTP() after delete error = %v, want not found", err)
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := memdb.New()
+	user := createUser(t, store, "walter@breakingbad.com")
+	now := time.Now()
+	store.SetClock(func() time.Time { return now })
+
+	_, err := store.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     "token",
+		UserID:    user.ID,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetUserForRefreshToken(ctx, "token")
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("GetUserForRefreshToken() = %v, %v, want %v", got.ID, err, user.ID)
+	}
+
+	store.SetClock(func() time.Time { return now.Add(time.Hour) })
+	if _, err := store.GetUserForRefreshToken(ctx, "token"); !database.IsNotFound(err) {
+		t.Errorf("GetUserForRefreshToken() after expiry error = %v, want not found", err)
+	}
+
+	store.SetClock(func() time.Time { return now })
+	err = store.RevokeRefreshToken(ctx, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetUserForRefreshToken(ctx, "token"); !database.IsNotFound(err) {
+		t.Errorf("GetUserForRefreshToken() after revoke error = %v, want not found", err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	store := memdb.New()
+	user := createUser(t, store, "walter@breakingbad.com")
+
+	errRollback := errors.New("rollback")
+	err := store.WithTx(ctx, func(q database.Querier) error {
+		_, err := q.UpdateUser(ctx, database.UpdateUserParams{ID: user.ID, Email: "heisenberg@breakingbad.com"})
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	if _, err := store.GetUserByEmail(ctx, "heisenberg@breakingbad.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("rolled back update is visible, error = %v", err)
+	}
+
+	err = store.WithTx(ctx, func(q database.Querier) error {
+		_, err := q.UpdateUser(ctx, database.UpdateUserParams{ID: user.ID, Email: "heisenberg@breakingbad.com"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetUserByEmail(ctx, "heisenberg@breakingbad.com"); err != nil {
+		t.Errorf("committed update is not visible, error = %v", err)
+	}
+}
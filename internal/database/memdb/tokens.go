@@ -0,0 +1,178 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	defer q.lock()()
+	if _, ok := q.tables.refreshTokens[arg.Token]; ok {
+		return database.RefreshToken{}, uniqueError("refresh_tokens_pkey")
+	}
+	now := q.timestamp()
+	token := database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	q.tables.refreshTokens[token.Token] = token
+	return token, nil
+}
+
+func (q *Queries) GetUserForRefreshToken(ctx context.Context, token string) (database.User, error) {
+	defer q.lock()()
+	refreshToken, ok := q.tables.refreshTokens[token]
+	if !ok || refreshToken.RevokedAt.Valid || !refreshToken.ExpiresAt.After(q.now()) {
+		return database.User{}, sql.ErrNoRows
+	}
+	user, ok := q.tables.users[refreshToken.UserID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
+	defer q.lock()()
+	refreshToken, ok := q.tables.refreshTokens[token]
+	if !ok {
+		return nil
+	}
+	now := q.timestamp()
+	refreshToken.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	refreshToken.UpdatedAt = now
+	q.tables.refreshTokens[token] = refreshToken
+	return nil
+}
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	defer q.lock()()
+	now := q.timestamp()
+	for token, refreshToken := range q.tables.refreshTokens {
+		if refreshToken.UserID != userID || refreshToken.RevokedAt.Valid {
+			continue
+		}
+		refreshToken.RevokedAt = sql.NullTime{Time: now, Valid: true}
+		refreshToken.UpdatedAt = now
+		q.tables.refreshTokens[token] = refreshToken
+	}
+	return nil
+}
+
+func (q *Queries) DeleteExpiredAccessTokenRevocations(ctx context.Context) error {
+	defer q.lock()()
+	now := q.now()
+	for jti, revoked := range q.tables.revokedAccessTokens {
+		if !revoked.ExpiresAt.After(now) {
+			delete(q.tables.revokedAccessTokens, jti)
+		}
+	}
+	return nil
+}
+
+func (q *Queries) DeleteExpiredUserTokenRevocations(ctx context.Context) error {
+	defer q.lock()()
+	now := q.now()
+	for userID, revocation := range q.tables.userRevocations {
+		if !revocation.ExpiresAt.After(now) {
+			delete(q.tables.userRevocations, userID)
+		}
+	}
+	return nil
+}
+
+func (q *Queries) GetUserAccessTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	defer q.lock()()
+	revocation, ok := q.tables.userRevocations[userID]
+	if !ok || !revocation.ExpiresAt.After(q.now()) {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return revocation.RevokedBefore, nil
+}
+
+func (q *Queries) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	defer q.lock()()
+	revoked, ok := q.tables.revokedAccessTokens[jti]
+	return ok && revoked.ExpiresAt.After(q.now()), nil
+}
+
+func (q *Queries) RevokeAccessToken(ctx context.Context, arg database.RevokeAccessTokenParams) error {
+	defer q.lock()()
+	if _, ok := q.tables.revokedAccessTokens[arg.Jti]; ok {
+		return nil
+	}
+	q.tables.revokedAccessTokens[arg.Jti] = database.RevokedAccessToken{
+		Jti:       arg.Jti,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+// RevokeUserAccessTokens upserts the revocation, only ever moving its
+// timestamps forward like the GREATEST in the SQL query.
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, arg database.RevokeUserAccessTokensParams) error {
+	defer q.lock()()
+	if err := q.userExists("user_token_revocations", arg.UserID); err != nil {
+		return err
+	}
+	revocation, ok := q.tables.userRevocations[arg.UserID]
+	if !ok {
+		q.tables.userRevocations[arg.UserID] = database.UserTokenRevocation{
+			UserID:        arg.UserID,
+			RevokedBefore: arg.RevokedBefore,
+			ExpiresAt:     arg.ExpiresAt,
+		}
+		return nil
+	}
+	if arg.RevokedBefore.After(revocation.RevokedBefore) {
+		revocation.RevokedBefore = arg.RevokedBefore
+	}
+	if arg.ExpiresAt.After(revocation.ExpiresAt) {
+		revocation.ExpiresAt = arg.ExpiresAt
+	}
+	q.tables.userRevocations[arg.UserID] = revocation
+	return nil
+}
+
+func (q *Queries) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (database.MagicLinkToken, error) {
+	defer q.lock()()
+	token, ok := q.tables.magicLinkTokens[tokenHash]
+	if !ok || token.UsedAt.Valid || !token.ExpiresAt.After(q.now()) {
+		return database.MagicLinkToken{}, sql.ErrNoRows
+	}
+	token.UsedAt = sql.NullTime{Time: q.timestamp(), Valid: true}
+	q.tables.magicLinkTokens[tokenHash] = token
+	return token, nil
+}
+
+func (q *Queries) CreateMagicLinkToken(ctx context.Context, arg database.CreateMagicLinkTokenParams) error {
+	defer q.lock()()
+	if _, ok := q.tables.magicLinkTokens[arg.TokenHash]; ok {
+		return uniqueError("magic_link_tokens_pkey")
+	}
+	q.tables.magicLinkTokens[arg.TokenHash] = database.MagicLinkToken{
+		TokenHash: arg.TokenHash,
+		Email:     arg.Email,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: q.timestamp(),
+	}
+	return nil
+}
+
+func (q *Queries) DeleteExpiredMagicLinkTokens(ctx context.Context) error {
+	defer q.lock()()
+	now := q.now()
+	for tokenHash, token := range q.tables.magicLinkTokens {
+		if !token.ExpiresAt.After(now) {
+			delete(q.tables.magicLinkTokens, tokenHash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg database.ConfirmUserTOTPParams) (database.UserTotp, error) {
+	defer q.lock()()
+	totp, ok := q.tables.userTOTP[arg.UserID]
+	if !ok {
+		return database.UserTotp{}, sql.ErrNoRows
+	}
+	now := q.timestamp()
+	totp.ConfirmedAt = sql.NullTime{Time: now, Valid: true}
+	totp.LastUsedStep = arg.LastUsedStep
+	totp.UpdatedAt = now
+	q.tables.userTOTP[arg.UserID] = totp
+	return totp, nil
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error {
+	defer q.lock()()
+	key := recoveryCodeKey{userID: arg.UserID, codeHash: arg.CodeHash}
+	if _, ok := q.tables.recoveryCodes[key]; ok {
+		return uniqueError("totp_recovery_codes_pkey")
+	}
+	if err := q.userExists("totp_recovery_codes", arg.UserID); err != nil {
+		return err
+	}
+	q.tables.recoveryCodes[key] = database.TotpRecoveryCode{
+		UserID:    arg.UserID,
+		CodeHash:  arg.CodeHash,
+		CreatedAt: q.timestamp(),
+	}
+	return nil
+}
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	defer q.lock()()
+	for key := range q.tables.recoveryCodes {
+		if key.userID == userID {
+			delete(q.tables.recoveryCodes, key)
+		}
+	}
+	return nil
+}
+
+func (q *Queries) GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error) {
+	defer q.lock()()
+	totp, ok := q.tables.userTOTP[userID]
+	if !ok {
+		return database.UserTotp{}, sql.ErrNoRows
+	}
+	return totp, nil
+}
+
+// UpsertUserTOTP replaces any previous secret and resets confirmation, as
+// the ON CONFLICT clause does.
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg database.UpsertUserTOTPParams) (database.UserTotp, error) {
+	defer q.lock()()
+	if err := q.userExists("user_totp", arg.UserID); err != nil {
+		return database.UserTotp{}, err
+	}
+	now := q.timestamp()
+	totp, ok := q.tables.userTOTP[arg.UserID]
+	if !ok {
+		totp = database.UserTotp{UserID: arg.UserID, CreatedAt: now}
+	}
+	totp.Secret = arg.Secret
+	totp.ConfirmedAt = sql.NullTime{}
+	totp.LastUsedStep = 0
+	totp.UpdatedAt = now
+	q.tables.userTOTP[arg.UserID] = totp
+	return totp, nil
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	defer q.lock()()
+	key := recoveryCodeKey{userID: arg.UserID, codeHash: arg.CodeHash}
+	code, ok := q.tables.recoveryCodes[key]
+	if !ok || code.UsedAt.Valid {
+		return 0, nil
+	}
+	code.UsedAt = sql.NullTime{Time: q.timestamp(), Valid: true}
+	q.tables.recoveryCodes[key] = code
+	return 1, nil
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg database.UseTOTPStepParams) (int64, error) {
+	defer q.lock()()
+	totp, ok := q.tables.userTOTP[arg.UserID]
+	if !ok || totp.LastUsedStep >= arg.LastUsedStep {
+		return 0, nil
+	}
+	totp.LastUsedStep = arg.LastUsedStep
+	totp.UpdatedAt = q.timestamp()
+	q.tables.userTOTP[arg.UserID] = totp
+	return 1, nil
+}
@@ -0,0 +1,53 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg database.CreateUserIdentityParams) (database.UserIdentity, error) {
+	defer q.lock()()
+	if _, ok := q.tables.identities[arg.ID]; ok {
+		return database.UserIdentity{}, uniqueError("user_identities_pkey")
+	}
+	if _, ok := q.identity(arg.Issuer, arg.Subject); ok {
+		return database.UserIdentity{}, uniqueError("user_identities_issuer_subject_key")
+	}
+	if err := q.userExists("user_identities", arg.UserID); err != nil {
+		return database.UserIdentity{}, err
+	}
+	identity := database.UserIdentity{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		Issuer:    arg.Issuer,
+		Subject:   arg.Subject,
+		Email:     arg.Email,
+		CreatedAt: q.timestamp(),
+	}
+	q.tables.identities[identity.ID] = identity
+	return identity, nil
+}
+
+func (q *Queries) GetUserByIdentity(ctx context.Context, arg database.GetUserByIdentityParams) (database.User, error) {
+	defer q.lock()()
+	identity, ok := q.identity(arg.Issuer, arg.Subject)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user, ok := q.tables.users[identity.UserID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *Queries) identity(issuer, subject string) (database.UserIdentity, bool) {
+	for _, identity := range q.tables.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, true
+		}
+	}
+	return database.UserIdentity{}, false
+}
@@ -0,0 +1,110 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	defer q.lock()()
+	if _, ok := q.tables.users[arg.ID]; ok {
+		return database.User{}, uniqueError("users_pkey")
+	}
+	if q.emailTaken(arg.Email, uuid.Nil) {
+		return database.User{}, uniqueError("users_email_key")
+	}
+	user := database.User{
+		ID:             arg.ID,
+		CreatedAt:      arg.CreatedAt,
+		UpdatedAt:      arg.UpdatedAt,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	q.tables.users[user.ID] = user
+	return user, nil
+}
+
+// DeleteAllUsers also removes every row with a foreign key to users, like
+// ON DELETE CASCADE. Refresh tokens have no foreign key and are kept.
+func (q *Queries) DeleteAllUsers(ctx context.Context) error {
+	defer q.lock()()
+	clear(q.tables.users)
+	clear(q.tables.chirps)
+	clear(q.tables.userRevocations)
+	clear(q.tables.userTOTP)
+	clear(q.tables.recoveryCodes)
+	clear(q.tables.apiKeys)
+	clear(q.tables.identities)
+	return nil
+}
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	defer q.lock()()
+	for _, user := range q.tables.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	defer q.lock()()
+	user, ok := q.tables.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	defer q.lock()()
+	user, ok := q.tables.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if q.emailTaken(arg.Email, arg.ID) {
+		return database.User{}, uniqueError("users_email_key")
+	}
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	user.UpdatedAt = q.timestamp()
+	q.tables.users[user.ID] = user
+	return user, nil
+}
+
+func (q *Queries) UpdateUserPasswordHash(ctx context.Context, arg database.UpdateUserPasswordHashParams) error {
+	defer q.lock()()
+	user, ok := q.tables.users[arg.ID]
+	if !ok {
+		return nil
+	}
+	user.HashedPassword = arg.HashedPassword
+	q.tables.users[user.ID] = user
+	return nil
+}
+
+func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	defer q.lock()()
+	user, ok := q.tables.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.IsChirpyRed = true
+	user.UpdatedAt = q.timestamp()
+	q.tables.users[user.ID] = user
+	return user, nil
+}
+
+// emailTaken reports whether a user other than except has the email.
+func (q *Queries) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range q.tables.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
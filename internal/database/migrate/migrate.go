@@ -0,0 +1,139 @@
+// Package migrate applies the embedded schema migrations with goose.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/sql/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// lockID is the Postgres advisory lock held while migrating, so replicas
+// booting at the same time apply migrations one after another instead of
+// racing. It is goose's default, spelled out because changing it between
+// releases would let old and new replicas migrate concurrently.
+const lockID = lock.DefaultLockID
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New loads the embedded migrations for driver, one of the database.Driver
+// constants.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	var provider *goose.Provider
+	var err error
+	switch driver {
+	case database.DriverPostgres:
+		var locker lock.SessionLocker
+		locker, err = lock.NewPostgresSessionLocker(lock.WithLockID(lockID))
+		if err != nil {
+			return nil, err
+		}
+		provider, err = goose.NewProvider(goose.DialectPostgres, db, schema.FS,
+			goose.WithSessionLocker(locker),
+			goose.WithDisableGlobalRegistry(true),
+		)
+	case database.DriverSQLite:
+		// a SQLite file has a single writer, so there is nothing to race
+		provider, err = goose.NewProvider(goose.DialectSQLite3, db, schema.SQLiteFS,
+			goose.WithDisableGlobalRegistry(true),
+		)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Count returns the number of embedded migrations.
+func (m *Migrator) Count() int {
+	return len(m.provider.ListSources())
+}
+
+// Up applies all pending migrations and reports each one to w.
+func (m *Migrator) Up(ctx context.Context, w io.Writer) error {
+	results, err := m.provider.Up(ctx)
+	for _, result := range results {
+		printResult(w, result)
+	}
+	if err != nil {
+		return fmt.Errorf("migrating up: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(w, "no pending migrations")
+	}
+	return nil
+}
+
+// Apply applies all pending migrations like Up, for the server on boot: each
+// one is reported to logger, so it follows the configured log format and
+// level instead of printing for a terminal.
+func (m *Migrator) Apply(ctx context.Context, logger *slog.Logger) error {
+	results, err := m.provider.Up(ctx)
+	for _, result := range results {
+		attrs := []any{
+			slog.Int64("version", result.Source.Version),
+			slog.String("migration", path.Base(result.Source.Path)),
+			slog.Duration("duration", result.Duration),
+		}
+		if result.Error != nil {
+			logger.Error("migration failed", append(attrs, slog.Any("err", result.Error))...)
+			continue
+		}
+		logger.Info("applied migration", attrs...)
+	}
+	if err != nil {
+		return fmt.Errorf("migrating up: %w", err)
+	}
+	if len(results) == 0 {
+		logger.Debug("no pending migrations")
+	}
+	return nil
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context, w io.Writer) error {
+	result, err := m.provider.Down(ctx)
+	if result != nil {
+		printResult(w, result)
+	}
+	if err != nil {
+		return fmt.Errorf("migrating down: %w", err)
+	}
+	return nil
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("reading migration status: %w", err)
+	}
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%-20s %s\n", appliedAt, path.Base(status.Source.Path))
+	}
+	return nil
+}
+
+func printResult(w io.Writer, result *goose.MigrationResult) {
+	name := path.Base(result.Source.Path)
+	if result.Error != nil {
+		fmt.Fprintf(w, "FAILED %-4s %s: %v\n", result.Direction, name, result.Error)
+		return
+	}
+	fmt.Fprintf(w, "OK     %-4s %s (%s)\n", result.Direction, name, result.Duration.Round(1e6))
+}
@@ -0,0 +1,93 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/database/migrate"
+	"github.com/maevlava/chirpy/internal/database/sqlite"
+	"github.com/maevlava/chirpy/sql/schema"
+)
+
+// TestEmbeddedMigrations checks every file in sql/schema is embedded and
+// parses as a goose migration. No database is contacted.
+func TestEmbeddedMigrations(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://localhost/unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, database.DriverPostgres)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	if got := migrator.Count(); got != len(files) {
+		t.Errorf("Count() = %d, want %d", got, len(files))
+	}
+}
+
+// TestSQLiteMigrations applies and rolls back every SQLite migration on a
+// scratch database, and checks they match the Postgres ones one to one.
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, database.DriverSQLite)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	postgres, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqliteFiles, err := fs.Glob(schema.SQLiteFS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(postgres, sqliteFiles) {
+		t.Errorf("sqlite migrations %v do not match postgres %v", sqliteFiles, postgres)
+	}
+
+	// as on boot, every applied migration is logged
+	var logs bytes.Buffer
+	err = migrator.Apply(ctx, slog.New(slog.NewJSONHandler(&logs, nil)))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got := strings.Count(logs.String(), `"msg":"applied migration"`); got != migrator.Count() {
+		t.Errorf("Apply() logged %d migrations, want %d:\n%s", got, migrator.Count(), logs.String())
+	}
+	err = migrator.Up(ctx, io.Discard)
+	if err != nil {
+		t.Fatalf("Up() with nothing pending error = %v", err)
+	}
+	for range migrator.Count() {
+		err = migrator.Down(ctx, io.Discard)
+		if err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// QueryObserver is called after every query made through an observed DBTX
+// with the query name, how long it took and the error it returned, if any.
+type QueryObserver func(name string, elapsed time.Duration, err error)
+
+// Observe wraps db so every query is reported to observe. It returns db
+// unchanged when observe is nil.
+func Observe(db DBTX, observe QueryObserver) DBTX {
+	if observe == nil {
+		return db
+	}
+	return &observedDB{db: db, observe: observe}
+}
+
+type observedDB struct {
+	db      DBTX
+	observe QueryObserver
+}
+
+func (o *observedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := o.db.ExecContext(ctx, query, args...)
+	o.observe(QueryName(query), time.Since(start), err)
+	return result, err
+}
+
+func (o *observedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return o.db.PrepareContext(ctx, query)
+}
+
+// QueryContext times the query until its first row is ready, not until
+// the rows are consumed.
+func (o *observedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := o.db.QueryContext(ctx, query, args...)
+	o.observe(QueryName(query), time.Since(start), err)
+	return rows, err
+}
+
+// QueryRowContext reports the errors known before the row is scanned. Some
+// drivers, e.g. SQLite, only return constraint violations from Scan.
+func (o *observedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := o.db.QueryRowContext(ctx, query, args...)
+	o.observe(QueryName(query), time.Since(start), row.Err())
+	return row
+}
+
+// QueryName returns the name from the sqlc "-- name: GetUserByID :one"
+// header of query, or "unnamed".
+func QueryName(query string) string {
+	header, _, _ := strings.Cut(query, "\n")
+	name, ok := strings.CutPrefix(strings.TrimSpace(header), "-- name: ")
+	if !ok {
+		return "unnamed"
+	}
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
@@ -6,24 +6,51 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error)
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (MagicLinkToken, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	CreateMagicLinkToken(ctx context.Context, arg CreateMagicLinkTokenParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
 	DeleteAllUsers(ctx context.Context) error
 	DeleteChirp(ctx context.Context, id uuid.UUID) error
+	DeleteExpiredAccessTokenRevocations(ctx context.Context) error
+	DeleteExpiredMagicLinkTokens(ctx context.Context) error
+	DeleteExpiredUserTokenRevocations(ctx context.Context) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
 	GetAllChirps(ctx context.Context) ([]Chirp, error)
 	GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error)
 	GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error)
+	GetUserAccessTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error)
 	GetUserForRefreshToken(ctx context.Context, token string) (User, error)
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (UserTotp, error)
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]ApiKey, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error)
+	RevokeAccessToken(ctx context.Context, arg RevokeAccessTokenParams) error
 	RevokeRefreshToken(ctx context.Context, token string) error
+	RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPasswordHash(ctx context.Context, arg UpdateUserPasswordHashParams) error
 	UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (User, error)
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row scanner) (database.ApiKey, error) {
+	var i database.ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING ` + apiKeyColumns
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	return scanAPIKey(q.db.QueryRowContext(ctx, createAPIKey,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.Scopes,
+	))
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT ` + apiKeyColumns + ` FROM api_keys
+WHERE key_hash = ?
+  AND revoked_at IS NULL`
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	return scanAPIKey(q.db.QueryRowContext(ctx, getAPIKeyByHash, keyHash))
+}
+
+const listAPIKeysByUser = `-- name: ListAPIKeysByUser :many
+SELECT ` + apiKeyColumns + ` FROM api_keys
+WHERE user_id = ?
+  AND revoked_at IS NULL
+ORDER BY created_at`
+
+func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listAPIKeysByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ApiKey
+	for rows.Next() {
+		i, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = ?3
+WHERE id = ?1
+  AND user_id = ?2
+  AND revoked_at IS NULL`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg database.RevokeAPIKeyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAPIKey, arg.ID, arg.UserID, q.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = ?2
+WHERE id = ?1`
+
+func (q *Queries) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchAPIKey, id, q.timestamp())
+	return err
+}
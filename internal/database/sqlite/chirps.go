@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const chirpColumns = `id, created_at, updated_at, body, user_id`
+
+func scanChirp(row scanner) (database.Chirp, error) {
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+	)
+	return i, err
+}
+
+func scanChirps(rows *sql.Rows, err error) ([]database.Chirp, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Chirp
+	for rows.Next() {
+		i, err := scanChirp(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createChirp = `-- name: CreateChirp :one
+INSERT INTO chirp(id, created_at, updated_at, body, user_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING ` + chirpColumns
+
+func (q *Queries) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	return scanChirp(q.db.QueryRowContext(ctx, createChirp,
+		arg.ID,
+		timestamp(arg.CreatedAt),
+		timestamp(arg.UpdatedAt),
+		arg.Body,
+		arg.UserID,
+	))
+}
+
+const deleteChirp = `-- name: DeleteChirp :exec
+DELETE FROM chirp
+WHERE id = ?`
+
+func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirp, id)
+	return err
+}
+
+const getAllChirps = `-- name: GetAllChirps :many
+SELECT ` + chirpColumns + ` FROM chirp
+ORDER BY created_at`
+
+func (q *Queries) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	return scanChirps(q.db.QueryContext(ctx, getAllChirps))
+}
+
+const getChirpById = `-- name: GetChirpById :one
+SELECT ` + chirpColumns + ` FROM chirp
+WHERE id = ?`
+
+func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return scanChirp(q.db.QueryRowContext(ctx, getChirpById, id))
+}
+
+const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
+SELECT ` + chirpColumns + ` FROM chirp
+WHERE user_id = ?
+ORDER BY created_at`
+
+func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return scanChirps(q.db.QueryContext(ctx, getChirpsByAuthor, userID))
+}
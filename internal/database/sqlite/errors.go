@@ -0,0 +1,26 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Without cgo go-sqlite3 is a stub that cannot open databases, so there
+// are no errors to classify and this file is left out.
+func init() {
+	database.RegisterConstraintErrors(database.ConstraintErrors{
+		IsUniqueViolation: func(err error) bool {
+			var sqliteErr sqlite3.Error
+			return errors.As(err, &sqliteErr) &&
+				(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+		},
+		IsForeignKeyViolation: func(err error) bool {
+			var sqliteErr sqlite3.Error
+			return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const consumeMagicLinkToken = `-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = ?2
+WHERE token_hash = ?1
+  AND used_at IS NULL
+  AND expires_at > ?2
+RETURNING token_hash, email, expires_at, used_at, created_at`
+
+func (q *Queries) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (database.MagicLinkToken, error) {
+	row := q.db.QueryRowContext(ctx, consumeMagicLinkToken, tokenHash, q.timestamp())
+	var i database.MagicLinkToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Email,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMagicLinkToken = `-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, email, expires_at)
+VALUES (?, ?, ?)`
+
+func (q *Queries) CreateMagicLinkToken(ctx context.Context, arg database.CreateMagicLinkTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createMagicLinkToken, arg.TokenHash, arg.Email, timestamp(arg.ExpiresAt))
+	return err
+}
+
+const deleteExpiredMagicLinkTokens = `-- name: DeleteExpiredMagicLinkTokens :exec
+DELETE FROM magic_link_tokens
+WHERE expires_at <= ?`
+
+func (q *Queries) DeleteExpiredMagicLinkTokens(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredMagicLinkTokens, q.timestamp())
+	return err
+}
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const createRefreshToken = `-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, user_id, expires_at)
+VALUES (?, ?, ?)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at`
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, timestamp(arg.ExpiresAt))
+	var i database.RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getUserForRefreshToken = `-- name: GetUserForRefreshToken :one
+SELECT ` + qualifiedUserColumns + `
+FROM users
+INNER JOIN refresh_tokens ON users.id = refresh_tokens.user_id
+WHERE refresh_tokens.token = ?1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > ?2`
+
+func (q *Queries) GetUserForRefreshToken(ctx context.Context, token string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserForRefreshToken, token, q.timestamp()))
+}
+
+const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = ?2, updated_at = ?2
+WHERE token = ?1`
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token, q.timestamp())
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = ?2, updated_at = ?2
+WHERE user_id = ?1
+  AND revoked_at IS NULL`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID, q.timestamp())
+	return err
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const deleteExpiredAccessTokenRevocations = `-- name: DeleteExpiredAccessTokenRevocations :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at <= ?`
+
+func (q *Queries) DeleteExpiredAccessTokenRevocations(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredAccessTokenRevocations, q.timestamp())
+	return err
+}
+
+const deleteExpiredUserTokenRevocations = `-- name: DeleteExpiredUserTokenRevocations :exec
+DELETE FROM user_token_revocations
+WHERE expires_at <= ?`
+
+func (q *Queries) DeleteExpiredUserTokenRevocations(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredUserTokenRevocations, q.timestamp())
+	return err
+}
+
+const getUserAccessTokensRevokedBefore = `-- name: GetUserAccessTokensRevokedBefore :one
+SELECT revoked_before FROM user_token_revocations
+WHERE user_id = ?1
+  AND expires_at > ?2`
+
+func (q *Queries) GetUserAccessTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getUserAccessTokensRevokedBefore, userID, q.timestamp())
+	var revoked_before time.Time
+	err := row.Scan(&revoked_before)
+	return revoked_before, err
+}
+
+const isAccessTokenRevoked = `-- name: IsAccessTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_access_tokens
+    WHERE jti = ?1
+      AND expires_at > ?2
+)`
+
+func (q *Queries) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isAccessTokenRevoked, jti, q.timestamp())
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const revokeAccessToken = `-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, expires_at)
+VALUES (?, ?)
+ON CONFLICT (jti) DO NOTHING`
+
+func (q *Queries) RevokeAccessToken(ctx context.Context, arg database.RevokeAccessTokenParams) error {
+	_, err := q.db.ExecContext(ctx, revokeAccessToken, arg.Jti, timestamp(arg.ExpiresAt))
+	return err
+}
+
+// max() with several arguments is SQLite's GREATEST.
+const revokeUserAccessTokens = `-- name: RevokeUserAccessTokens :exec
+INSERT INTO user_token_revocations (user_id, revoked_before, expires_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET revoked_before = max(user_token_revocations.revoked_before, excluded.revoked_before),
+    expires_at = max(user_token_revocations.expires_at, excluded.expires_at)`
+
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, arg database.RevokeUserAccessTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserAccessTokens, arg.UserID, timestamp(arg.RevokedBefore), timestamp(arg.ExpiresAt))
+	return err
+}
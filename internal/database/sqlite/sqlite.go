@@ -0,0 +1,118 @@
+// Package sqlite implements database.Store on SQLite, for local development
+// and single-binary deployments. The queries are hand-written translations
+// of sql/queries for the schema in sql/schema/sqlite and return the same
+// models as the Postgres code generated by sqlc. They keep the sqlc name
+// headers so query metrics are labelled the same on both databases.
+//
+// Timestamps are bound as UTC text in a fixed layout so they compare
+// correctly as strings, and NOW() is bound from Go for the same reason.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the database/sql driver registered by go-sqlite3.
+const DriverName = "sqlite3"
+
+// timestampLayout matches the strftime defaults in the schema and keeps the
+// microsecond precision of a Postgres TIMESTAMP.
+const timestampLayout = "2006-01-02 15:04:05.000000"
+
+// defaultParams are added to a DSN that does not set them. Foreign keys are
+// off by default in SQLite, WAL lets readers run alongside the writer, and
+// transactions take the write lock up front so two of them cannot deadlock
+// upgrading from a read lock.
+var defaultParams = []string{
+	"_foreign_keys=on",
+	"_journal_mode=WAL",
+	"_busy_timeout=5000",
+	"_txlock=immediate",
+}
+
+// DSN returns dsn, a file path or file: URI, with defaultParams added.
+func DSN(dsn string) string {
+	for _, param := range defaultParams {
+		name, _, _ := strings.Cut(param, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + param
+		} else {
+			dsn += "?" + param
+		}
+	}
+	return dsn
+}
+
+// Open opens the SQLite database at dsn with the default parameters.
+func Open(dsn string) (*sql.DB, error) {
+	return sql.Open(DriverName, DSN(dsn))
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+type Queries struct {
+	db  database.DBTX
+	now func() time.Time
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func New(db database.DBTX) *Queries {
+	return &Queries{db: db, now: time.Now}
+}
+
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return q.withDB(tx)
+}
+
+func (q *Queries) withDB(db database.DBTX) *Queries {
+	return &Queries{
+		db:  db,
+		now: q.now,
+	}
+}
+
+// timestamp returns NOW() as bound in queries.
+func (q *Queries) timestamp() string {
+	return timestamp(q.now())
+}
+
+// Store is the database.Store backed by a SQLite *sql.DB.
+type Store struct {
+	*Queries
+	db      *sql.DB
+	observe database.QueryObserver
+}
+
+var _ database.Store = (*Store)(nil)
+
+func NewStore(db *sql.DB) *Store {
+	return NewObservedStore(db, nil)
+}
+
+// NewObservedStore returns a Store reporting every query, including those
+// in transactions, to observe.
+func NewObservedStore(db *sql.DB, observe database.QueryObserver) *Store {
+	return &Store{
+		Queries: New(database.Observe(db, observe)),
+		db:      db,
+		observe: observe,
+	}
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(database.Querier) error) error {
+	return database.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(s.Queries.withDB(database.Observe(tx, s.observe)))
+	})
+}
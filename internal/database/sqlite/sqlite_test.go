@@ -0,0 +1,300 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/database/migrate"
+	"github.com/maevlava/chirpy/internal/database/sqlite"
+)
+
+func newStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	return sqlite.NewStore(openDB(t))
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, database.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up(context.Background(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createUser(t *testing.T, store *sqlite.Store, email string) database.User {
+	t.Helper()
+	now := time.Now()
+	user, err := store.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Email:     email,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) error = %v", email, err)
+	}
+	return user
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	walter := createUser(t, store, "walter@breakingbad.com")
+
+	got, err := store.GetUserByEmail(ctx, walter.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != walter.ID || !got.CreatedAt.Equal(walter.CreatedAt) || got.HashedPassword.Valid || got.IsChirpyRed {
+		t.Errorf("GetUserByEmail() = %+v, want %+v", got, walter)
+	}
+
+	_, err = store.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Email: walter.Email})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("CreateUser() with taken email error = %v, want unique violation", err)
+	}
+
+	red, err := store.UpgradeUserToChirpyRed(ctx, walter.ID)
+	if err != nil || !red.IsChirpyRed {
+		t.Errorf("UpgradeUserToChirpyRed() = %+v, %v", red, err)
+	}
+	_, err = store.GetUserByID(ctx, uuid.New())
+	if !database.IsNotFound(err) {
+		t.Errorf("GetUserByID() for unknown id error = %v, want not found", err)
+	}
+}
+
+func TestChirpsCascade(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	walter := createUser(t, store, "walter@breakingbad.com")
+
+	_, err := store.CreateChirp(ctx, database.CreateChirpParams{ID: uuid.New(), Body: "orphan", UserID: uuid.New()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("CreateChirp() for unknown user error = %v, want foreign key violation", err)
+	}
+
+	start := time.Now()
+	for i, body := range []string{"I am the one who knocks", "Say my name"} {
+		at := start.Add(time.Duration(i) * time.Second)
+		_, err := store.CreateChirp(ctx, database.CreateChirpParams{ID: uuid.New(), CreatedAt: at, UpdatedAt: at, Body: body, UserID: walter.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	chirps, err := store.GetChirpsByAuthor(ctx, walter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chirps) != 2 || chirps[0].Body != "I am the one who knocks" {
+		t.Errorf("GetChirpsByAuthor() = %+v, want both chirps oldest first", chirps)
+	}
+
+	err = store.DeleteAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chirps, err = store.GetAllChirps(ctx)
+	if err != nil || len(chirps) != 0 {
+		t.Errorf("GetAllChirps() after deleting users = %+v, %v", chirps, err)
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	walter := createUser(t, store, "walter@breakingbad.com")
+
+	for token, expiresAt := range map[string]time.Time{
+		"valid":   time.Now().Add(time.Hour),
+		"expired": time.Now().Add(-time.Second),
+	} {
+		_, err := store.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: token, UserID: walter.ID, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user, err := store.GetUserForRefreshToken(ctx, "valid")
+	if err != nil || user.ID != walter.ID {
+		t.Errorf("GetUserForRefreshToken(valid) = %v, %v", user.ID, err)
+	}
+	if _, err := store.GetUserForRefreshToken(ctx, "expired"); !database.IsNotFound(err) {
+		t.Errorf("GetUserForRefreshToken(expired) error = %v, want not found", err)
+	}
+
+	err = store.RevokeUserRefreshTokens(ctx, walter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetUserForRefreshToken(ctx, "valid"); !database.IsNotFound(err) {
+		t.Errorf("GetUserForRefreshToken() after revoke error = %v, want not found", err)
+	}
+}
+
+func TestRevokeUserAccessTokensKeepsLatest(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	walter := createUser(t, store, "walter@breakingbad.com")
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	for _, revokedBefore := range []time.Time{now, now.Add(-time.Minute)} {
+		err := store.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{
+			UserID:        walter.ID,
+			RevokedBefore: revokedBefore,
+			ExpiresAt:     now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := store.GetUserAccessTokensRevokedBefore(ctx, walter.ID)
+	if err != nil || !got.Equal(now) {
+		t.Errorf("GetUserAccessTokensRevokedBefore() = %s, %v, want %s", got, err, now)
+	}
+}
+
+// TestTokenRevocations runs the denylist over SQLite, where expiry and
+// cutoffs are compared as timestamp text rather than as TIMESTAMP values.
+func TestTokenRevocations(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	store := sqlite.NewStore(db)
+	walter := createUser(t, store, "walter@breakingbad.com")
+	denylist := auth.NewStoreDenylist(store, time.Hour)
+	now := time.Now()
+
+	claimsFor := func(jti string, iat time.Time) *jwt.RegisteredClaims {
+		return &jwt.RegisteredClaims{
+			ID:       jti,
+			Subject:  walter.ID.String(),
+			IssuedAt: jwt.NewNumericDate(iat),
+		}
+	}
+
+	err := denylist.RevokeToken(ctx, "revoked", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// expires within the current second, which only the fractional part of
+	// the stored text tells apart from now
+	err = denylist.RevokeToken(ctx, "expired", now.Add(-time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for jti, want := range map[string]bool{"revoked": true, "expired": false, "other": false} {
+		revoked, err := denylist.IsRevoked(ctx, claimsFor(jti, now))
+		if err != nil || revoked != want {
+			t.Errorf("IsRevoked(%s) = %v, %v, want %v", jti, revoked, err, want)
+		}
+	}
+
+	err = denylist.RevokeUserTokens(ctx, walter.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		iat  time.Time
+		want bool
+	}{
+		"before":      {now.Add(-time.Minute), true},
+		"just before": {now.Add(-time.Millisecond), true},
+		"just after":  {now.Add(10 * time.Millisecond), false},
+	} {
+		revoked, err := denylist.IsRevoked(ctx, claimsFor("", tc.iat))
+		if err != nil || revoked != tc.want {
+			t.Errorf("IsRevoked() for token issued %s revocation = %v, %v, want %v", name, revoked, err, tc.want)
+		}
+	}
+
+	// the sweep on the next revocation drops the expired token
+	err = denylist.RevokeToken(ctx, "another", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM revoked_access_tokens").Scan(&count)
+	if err != nil || count != 2 {
+		t.Errorf("revoked_access_tokens has %d rows, %v, want 2", count, err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	walter := createUser(t, store, "walter@breakingbad.com")
+
+	errRollback := errors.New("rollback")
+	err := store.WithTx(ctx, func(q database.Querier) error {
+		_, err := q.UpdateUser(ctx, database.UpdateUserParams{ID: walter.ID, Email: "heisenberg@breakingbad.com"})
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	if _, err := store.GetUserByEmail(ctx, "heisenberg@breakingbad.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("rolled back update is visible, error = %v", err)
+	}
+}
+
+func TestObservedStore(t *testing.T) {
+	ctx := context.Background()
+	var queries []string
+	store := sqlite.NewObservedStore(openDB(t), func(name string, elapsed time.Duration, err error) {
+		if err != nil {
+			name += " failed"
+		}
+		queries = append(queries, name)
+	})
+
+	err := store.WithTx(ctx, func(q database.Querier) error {
+		_, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Email: "walter@breakingbad.com"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.GetUserByEmail(ctx, "walter@breakingbad.com")
+	store.GetUserByEmail(ctx, "jesse@breakingbad.com")
+	store.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{UserID: uuid.New()})
+
+	want := []string{"CreateUser", "GetUserByEmail", "GetUserByEmail", "RevokeUserAccessTokens failed"}
+	if !slices.Equal(queries, want) {
+		t.Errorf("observed queries = %q, want %q", queries, want)
+	}
+}
+
+func TestDSN(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"chirpy.db": "chirpy.db?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate",
+		"file:chirpy.db?_busy_timeout=100&_foreign_keys=off": "file:chirpy.db?_busy_timeout=100&_foreign_keys=off&_journal_mode=WAL&_txlock=immediate",
+	} {
+		if got := sqlite.DSN(dsn); got != want {
+			t.Errorf("DSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
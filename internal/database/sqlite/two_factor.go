@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const userTOTPColumns = `user_id, secret, confirmed_at, last_used_step, created_at, updated_at`
+
+func scanUserTOTP(row scanner) (database.UserTotp, error) {
+	var i database.UserTotp
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const confirmUserTOTP = `-- name: ConfirmUserTOTP :one
+UPDATE user_totp
+SET confirmed_at = ?3,
+    last_used_step = ?2,
+    updated_at = ?3
+WHERE user_id = ?1
+RETURNING ` + userTOTPColumns
+
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg database.ConfirmUserTOTPParams) (database.UserTotp, error) {
+	return scanUserTOTP(q.db.QueryRowContext(ctx, confirmUserTOTP, arg.UserID, arg.LastUsedStep, q.timestamp()))
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes (user_id, code_hash)
+VALUES (?, ?)`
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, createRecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = ?`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, userID)
+	return err
+}
+
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT ` + userTOTPColumns + ` FROM user_totp
+WHERE user_id = ?`
+
+func (q *Queries) GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error) {
+	return scanUserTOTP(q.db.QueryRowContext(ctx, getUserTOTP, userID))
+}
+
+const upsertUserTOTP = `-- name: UpsertUserTOTP :one
+INSERT INTO user_totp (user_id, secret)
+VALUES (?1, ?2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret,
+    confirmed_at = NULL,
+    last_used_step = 0,
+    updated_at = ?3
+RETURNING ` + userTOTPColumns
+
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg database.UpsertUserTOTPParams) (database.UserTotp, error) {
+	return scanUserTOTP(q.db.QueryRowContext(ctx, upsertUserTOTP, arg.UserID, arg.Secret, q.timestamp()))
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = ?3
+WHERE user_id = ?1
+  AND code_hash = ?2
+  AND used_at IS NULL`
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRecoveryCode, arg.UserID, arg.CodeHash, q.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = ?2,
+    updated_at = ?3
+WHERE user_id = ?1
+  AND last_used_step < ?2`
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg database.UseTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPStep, arg.UserID, arg.LastUsedStep, q.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const createUserIdentity = `-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, user_id, issuer, subject, email)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, issuer, subject, email, created_at`
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg database.CreateUserIdentityParams) (database.UserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, createUserIdentity,
+		arg.ID,
+		arg.UserID,
+		arg.Issuer,
+		arg.Subject,
+		arg.Email,
+	)
+	var i database.UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserByIdentity = `-- name: GetUserByIdentity :one
+SELECT ` + qualifiedUserColumns + `
+FROM users
+INNER JOIN user_identities ON users.id = user_identities.user_id
+WHERE user_identities.issuer = ?
+  AND user_identities.subject = ?`
+
+func (q *Queries) GetUserByIdentity(ctx context.Context, arg database.GetUserByIdentityParams) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByIdentity, arg.Issuer, arg.Subject))
+}
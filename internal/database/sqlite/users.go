@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const userColumns = `id, created_at, updated_at, email, hashed_password, is_chirpy_red`
+
+// qualifiedUserColumns is userColumns for queries joining users to another
+// table with the same column names.
+const qualifiedUserColumns = `users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (database.User, error) {
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+VALUES (?, ?, ?, ?, ?)
+RETURNING ` + userColumns
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, createUser,
+		arg.ID,
+		timestamp(arg.CreatedAt),
+		timestamp(arg.UpdatedAt),
+		arg.Email,
+		arg.HashedPassword,
+	))
+}
+
+const deleteAllUsers = `-- name: DeleteAllUsers :exec
+DELETE FROM users`
+
+func (q *Queries) DeleteAllUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllUsers)
+	return err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT ` + userColumns + ` FROM users
+WHERE email = ?`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByEmail, email))
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT ` + userColumns + ` FROM users
+WHERE id = ?`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByID, id))
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET email = ?2,
+    hashed_password = ?3,
+    updated_at = ?4
+WHERE id = ?1
+RETURNING ` + userColumns
+
+func (q *Queries) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword, q.timestamp()))
+}
+
+const updateUserPasswordHash = `-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET hashed_password = ?2
+WHERE id = ?1`
+
+func (q *Queries) UpdateUserPasswordHash(ctx context.Context, arg database.UpdateUserPasswordHashParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPasswordHash, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const upgradeUserToChirpyRed = `-- name: UpgradeUserToChirpyRed :one
+UPDATE users
+SET is_chirpy_red = TRUE,
+    updated_at = ?2
+WHERE id = ?1
+RETURNING ` + userColumns
+
+func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, upgradeUserToChirpyRed, id, q.timestamp()))
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Drivers a Store can be backed by.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Store is a Querier that can also run a group of queries atomically.
+type Store interface {
+	Querier
+	// WithTx runs fn in a transaction. The transaction is committed when fn
+	// returns nil and rolled back otherwise. Queries made through the
+	// Querier passed to fn are part of the transaction; queries on the
+	// Store itself are not.
+	WithTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// SQLStore is the Store backed by a *sql.DB.
+type SQLStore struct {
+	*Queries
+	db      *sql.DB
+	observe QueryObserver
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func NewStore(db *sql.DB) *SQLStore {
+	return NewObservedStore(db, nil)
+}
+
+// NewObservedStore returns a Store reporting every query, including those
+// in transactions, to observe.
+func NewObservedStore(db *sql.DB, observe QueryObserver) *SQLStore {
+	return &SQLStore{
+		Queries: New(Observe(db, observe)),
+		db:      db,
+		observe: observe,
+	}
+}
+
+func (s *SQLStore) WithTx(ctx context.Context, fn func(Querier) error) error {
+	return InTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(New(Observe(tx, s.observe)))
+	})
+}
+
+// InTx runs fn in a transaction on db, committing when fn returns nil and
+// rolling back otherwise. Store implementations build WithTx on it.
+func InTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rollbackErr))
+		}
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package http_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/config"
+)
+
+func TestMetrics(t *testing.T) {
+	server := newTestServer(t)
+
+	server.do("GET", "/app", nil)
+	server.do("GET", "/app/", nil)
+	server.do("GET", "/api/healthz", nil)
+
+	rr := server.do("GET", "/admin/metrics", nil)
+	expectStatus(t, rr, http.StatusOK)
+	if !strings.Contains(rr.Body.String(), "visited 2 times") {
+		t.Errorf("metrics page does not count only web app visits:\n%s", rr.Body.String())
+	}
+
+	server.do("POST", "/admin/reset", nil)
+	rr = server.do("GET", "/admin/metrics", nil)
+	if !strings.Contains(rr.Body.String(), "visited 0 times") {
+		t.Errorf("reset does not clear visits:\n%s", rr.Body.String())
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	server := newTestServer(t)
+
+	walter := server.createUser("walter@breakingbad.com")
+	server.do("GET", "/api/chirps/"+uuid.NewString(), nil, bearer(walter.Token)...)
+	server.do("GET", "/api/chirps/"+uuid.NewString(), nil, bearer(walter.Token)...)
+
+	rr := server.do("GET", "/admin/metrics/prometheus", nil)
+	expectStatus(t, rr, http.StatusOK)
+	for _, want := range []string{
+		`chirpy_http_requests_total{code="201",method="post",route="/api/users"} 1`,
+		`chirpy_http_requests_total{code="404",method="get",route="/api/chirps/{chirpId}"} 2`,
+		`chirpy_http_request_duration_seconds_count{method="post",route="/api/login"} 1`,
+		`chirpy_http_requests_in_flight 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("exposition does not contain %s", want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	t.Run("Dev", func(t *testing.T) {
+		server := newTestServer(t)
+		server.createUser("walter@breakingbad.com")
+
+		rr := server.do("POST", "/admin/reset", nil)
+		expectStatus(t, rr, http.StatusOK)
+		rr = server.do("POST", "/api/login", map[string]string{"email": "walter@breakingbad.com", "password": testPassword})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("Production", func(t *testing.T) {
+		server := newTestServer(t, func(cfg *config.ApiConfig) {
+			cfg.Platform = config.PlatformProduction
+		})
+		server.createUser("walter@breakingbad.com")
+
+		rr := server.do("POST", "/admin/reset", nil)
+		expectStatus(t, rr, http.StatusForbidden)
+		server.login("walter@breakingbad.com", testPassword)
+	})
+}
+
+func TestPolkaWebhooks(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	upgrade := map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": walter.ID.String()},
+	}
+	apiKey := []string{"Authorization", "ApiKey " + testPolkaKey}
+
+	rr := server.do("POST", "/api/polka/webhooks", upgrade)
+	expectStatus(t, rr, http.StatusUnauthorized)
+	rr = server.do("POST", "/api/polka/webhooks", upgrade, "Authorization", "ApiKey wrong")
+	expectStatus(t, rr, http.StatusUnauthorized)
+
+	rr = server.do("POST", "/api/polka/webhooks", map[string]any{"event": "user.payment_failed"}, apiKey...)
+	expectStatus(t, rr, http.StatusNoContent)
+	if server.login("walter@breakingbad.com", testPassword).IsChirpyRed {
+		t.Fatal("unrelated event upgraded the user")
+	}
+
+	// Polka may add fields and a charset at any time
+	upgrade["id"] = uuid.NewString()
+	upgrade["data"] = map[string]string{"user_id": walter.ID.String(), "plan": "red"}
+	rr = server.do("POST", "/api/polka/webhooks", upgrade, append(apiKey, "Content-Type", "application/json; charset=utf-8")...)
+	expectStatus(t, rr, http.StatusNoContent)
+	if !server.login("walter@breakingbad.com", testPassword).IsChirpyRed {
+		t.Error("user is not upgraded")
+	}
+
+	invalid := map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": "walter"},
+	}
+	rr = server.do("POST", "/api/polka/webhooks", invalid, apiKey...)
+	expectStatus(t, rr, http.StatusBadRequest)
+
+	unknown := map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": uuid.NewString()},
+	}
+	rr = server.do("POST", "/api/polka/webhooks", unknown, apiKey...)
+	expectStatus(t, rr, http.StatusNotFound)
+}
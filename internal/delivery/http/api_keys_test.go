@@ -0,0 +1,77 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func TestAPIKeys(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	createKey := func(t *testing.T, name string, scopes ...string) app.APIKeyResponse {
+		t.Helper()
+		rr := server.do("POST", "/api/keys", map[string]any{"name": name, "scopes": scopes}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusCreated)
+		return decodeBody[app.APIKeyResponse](t, rr)
+	}
+	writer := createKey(t, "writer", auth.ScopeChirpsWrite)
+	if writer.Key == "" {
+		t.Fatal("created key is not returned")
+	}
+
+	t.Run("InvalidScope", func(t *testing.T) {
+		rr := server.do("POST", "/api/keys", map[string]any{"name": "bad", "scopes": []string{"everything"}}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusBadRequest)
+	})
+	t.Run("KeyCannotManageKeys", func(t *testing.T) {
+		rr := server.do("GET", "/api/keys", nil, "Authorization", "ApiKey "+writer.Key)
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("List", func(t *testing.T) {
+		rr := server.do("GET", "/api/keys", nil, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusOK)
+		keys := decodeBody[[]app.APIKeyResponse](t, rr)
+		if len(keys) != 1 || keys[0].ID != writer.ID || keys[0].Key != "" {
+			t.Errorf("keys = %+v, want only %s without the secret", keys, writer.ID)
+		}
+	})
+	t.Run("CreateChirpWithKey", func(t *testing.T) {
+		rr := server.do("POST", "/api/chirps", map[string]string{"body": "Say my name"}, "Authorization", "ApiKey "+writer.Key)
+		expectStatus(t, rr, http.StatusCreated)
+	})
+	t.Run("ReadOnlyKey", func(t *testing.T) {
+		reader := createKey(t, "reader", auth.ScopeChirpsRead)
+		rr := server.do("GET", "/api/chirps", nil, "Authorization", "ApiKey "+reader.Key)
+		expectStatus(t, rr, http.StatusOK)
+		rr = server.do("POST", "/api/chirps", map[string]string{"body": "Say my name"}, "Authorization", "ApiKey "+reader.Key)
+		expectStatus(t, rr, http.StatusForbidden)
+	})
+	t.Run("ReadWithKey", func(t *testing.T) {
+		// chirps:write includes reading
+		rr := server.do("GET", "/api/chirps", nil, "Authorization", "ApiKey "+writer.Key)
+		expectStatus(t, rr, http.StatusOK)
+		// reads are public, but a key that is sent must be valid
+		rr = server.do("GET", "/api/chirps", nil, "Authorization", "ApiKey chirpy_made-up")
+		expectStatus(t, rr, http.StatusUnauthorized)
+		rr = server.do("GET", "/api/chirps", nil)
+		expectStatus(t, rr, http.StatusOK)
+	})
+	t.Run("Revoke", func(t *testing.T) {
+		rr := server.do("DELETE", "/api/keys/"+writer.ID.String(), nil, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusNoContent)
+		rr = server.do("DELETE", "/api/keys/"+writer.ID.String(), nil, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusNotFound)
+		rr = server.do("POST", "/api/chirps", map[string]string{"body": "Say my name"}, "Authorization", "ApiKey "+writer.Key)
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("RevokeOtherUsersKey", func(t *testing.T) {
+		jesse := server.createUser("jesse@breakingbad.com")
+		key := createKey(t, "another", auth.ScopeChirpsWrite)
+		rr := server.do("DELETE", "/api/keys/"+key.ID.String(), nil, bearer(jesse.Token)...)
+		expectStatus(t, rr, http.StatusNotFound)
+	})
+}
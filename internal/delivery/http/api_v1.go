@@ -0,0 +1,34 @@
+package http
+
+import (
+	"github.com/maevlava/chirpy/internal/app"
+)
+
+// v1Routes are the routes of /api/v1/, also served on the unversioned
+// /api/ paths for clients written before versioning.
+func v1Routes(app *app.Application) routeTable {
+	return routeTable{
+		"GET /chirps":               app.HandlerGetChirps,
+		"POST /chirps":              app.HandlerChirps,
+		"PUT /users":                app.HandlerUserUpdate,
+		"GET /chirps/{chirpId}":     app.HandlerGetChirpByID,
+		"DELETE /chirps/{chirpId}":  app.HandlerDeleteChirpByID,
+		"GET /healthz":              app.HandlerReadiness,
+		"GET /readyz":               app.HandlerReadyz,
+		"POST /login":               app.HandlerLogin,
+		"GET /login/oidc":           app.HandlerOIDCLogin,
+		"GET /login/oidc/callback":  app.HandlerOIDCCallback,
+		"POST /login/magic":         app.HandlerMagicLinkRequest,
+		"POST /login/magic/confirm": app.HandlerMagicLinkConfirm,
+		"POST /login/2fa":           app.HandlerLoginTwoFactor,
+		"POST /2fa/enroll":          app.HandlerTwoFactorEnroll,
+		"POST /2fa/confirm":         app.HandlerTwoFactorConfirm,
+		"POST /keys":                app.HandlerCreateAPIKey,
+		"GET /keys":                 app.HandlerListAPIKeys,
+		"DELETE /keys/{keyId}":      app.HandlerRevokeAPIKey,
+		"POST /users":               app.HandlerUsers,
+		"POST /refresh":             app.HandlerRefreshToken,
+		"POST /revoke":              app.HandlerRevokeToken,
+		"POST /polka/webhooks":      app.HandlerPolkaWebhooks,
+	}
+}
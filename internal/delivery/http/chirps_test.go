@@ -0,0 +1,189 @@
+package http_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+func (s *testServer) createChirp(token, body string) database.Chirp {
+	s.t.Helper()
+	rr := s.do("POST", "/api/chirps", map[string]string{"body": body}, bearer(token)...)
+	expectStatus(s.t, rr, http.StatusCreated)
+	return decodeBody[database.Chirp](s.t, rr)
+}
+
+func TestCreateChirp(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	chirp := server.createChirp(walter.Token, "Say my name")
+	if chirp.Body != "Say my name" || chirp.UserID != walter.ID {
+		t.Errorf("created chirp = %+v", chirp)
+	}
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		rr := server.do("POST", "/api/chirps", map[string]string{"body": "Say my name"})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("TooLong", func(t *testing.T) {
+		rr := server.do("POST", "/api/chirps", map[string]string{"body": strings.Repeat("a", 201)}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusBadRequest)
+	})
+}
+
+func TestListChirps(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	jesse := server.createUser("jesse@breakingbad.com")
+	first := server.createChirp(walter.Token, "I am the one who knocks")
+	second := server.createChirp(jesse.Token, "Yeah, science!")
+	third := server.createChirp(walter.Token, "Say my name")
+
+	ids := func(chirps []database.Chirp) []string {
+		var ids []string
+		for _, chirp := range chirps {
+			ids = append(ids, chirp.ID.String())
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  []database.Chirp
+	}{
+		{"All", "", []database.Chirp{first, second, third}},
+		{"Desc", "?sort=desc", []database.Chirp{third, second, first}},
+		{"ByAuthor", "?author_id=" + walter.ID.String(), []database.Chirp{first, third}},
+		{"ByAuthorDesc", "?sort=desc&author_id=" + walter.ID.String(), []database.Chirp{third, first}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := server.do("GET", "/api/chirps"+tc.query, nil)
+			expectStatus(t, rr, http.StatusOK)
+			got := ids(decodeBody[[]database.Chirp](t, rr))
+			if strings.Join(got, ",") != strings.Join(ids(tc.want), ",") {
+				t.Errorf("chirps = %v, want %v", got, ids(tc.want))
+			}
+		})
+	}
+
+	t.Run("InvalidAuthor", func(t *testing.T) {
+		rr := server.do("GET", "/api/chirps?author_id=walter", nil)
+		expectStatus(t, rr, http.StatusBadRequest)
+	})
+}
+
+func TestGetChirp(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	chirp := server.createChirp(walter.Token, "Say my name")
+
+	rr := server.do("GET", "/api/chirps/"+chirp.ID.String(), nil)
+	expectStatus(t, rr, http.StatusOK)
+	if got := decodeBody[database.Chirp](t, rr); got.ID != chirp.ID || got.Body != chirp.Body {
+		t.Errorf("chirp = %+v, want %+v", got, chirp)
+	}
+
+	rr = server.do("GET", "/api/chirps/"+walter.ID.String(), nil)
+	expectStatus(t, rr, http.StatusNotFound)
+
+	rr = server.do("GET", "/api/chirps/heisenberg", nil)
+	expectStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestChirpsCaching(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	chirp := server.createChirp(walter.Token, "Say my name")
+
+	for path, policy := range map[string]string{
+		"/api/chirps":                      "public, no-cache",
+		"/api/chirps/" + chirp.ID.String(): "public, max-age=60",
+	} {
+		rr := server.do("GET", path, nil)
+		expectStatus(t, rr, http.StatusOK)
+		etag := rr.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `W/"`) {
+			t.Fatalf("%s: ETag = %q, want a weak ETag", path, etag)
+		}
+		if got := rr.Header().Get("Cache-Control"); got != policy {
+			t.Errorf("%s: Cache-Control = %q, want %q", path, got, policy)
+		}
+
+		rr = server.do("GET", path, nil, "If-None-Match", etag)
+		expectStatus(t, rr, http.StatusNotModified)
+		if rr.Body.Len() != 0 {
+			t.Errorf("%s: 304 has a body", path)
+		}
+	}
+
+	rr := server.do("GET", "/api/chirps", nil)
+	etag := rr.Header().Get("ETag")
+	server.createChirp(walter.Token, "I am the one who knocks")
+	rr = server.do("GET", "/api/chirps", nil, "If-None-Match", etag)
+	expectStatus(t, rr, http.StatusOK)
+	if rr.Header().Get("ETag") == etag {
+		t.Error("ETag did not change with the list")
+	}
+
+	rr = server.do("GET", "/api/chirps/"+walter.ID.String(), nil)
+	expectStatus(t, rr, http.StatusNotFound)
+	if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("404 Cache-Control = %q, want no-store", got)
+	}
+	rr = server.do("GET", "/api/healthz", nil)
+	if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("uncached route Cache-Control = %q, want no-store", got)
+	}
+}
+
+func TestChirpsCompression(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	for range 20 {
+		server.createChirp(walter.Token, "I am not in danger, I am the danger")
+	}
+
+	rr := server.do("GET", "/api/chirps", nil, "Accept-Encoding", "gzip")
+	expectStatus(t, rr, http.StatusOK)
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	body, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chirps []database.Chirp
+	if err := json.NewDecoder(body).Decode(&chirps); err != nil || len(chirps) != 20 {
+		t.Errorf("decoded %d chirps, err %v, want 20", len(chirps), err)
+	}
+}
+
+func TestDeleteChirp(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	jesse := server.createUser("jesse@breakingbad.com")
+	chirp := server.createChirp(walter.Token, "Say my name")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	rr := server.do("DELETE", path, nil)
+	expectStatus(t, rr, http.StatusUnauthorized)
+
+	rr = server.do("DELETE", path, nil, bearer(jesse.Token)...)
+	expectStatus(t, rr, http.StatusForbidden)
+
+	rr = server.do("DELETE", path, nil, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusNoContent)
+
+	rr = server.do("GET", path, nil)
+	expectStatus(t, rr, http.StatusNotFound)
+	rr = server.do("DELETE", path, nil, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusNotFound)
+
+	rr = server.do("DELETE", "/api/chirps/heisenberg", nil, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusBadRequest)
+}
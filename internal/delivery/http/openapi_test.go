@@ -0,0 +1,72 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	httpdelivery "github.com/maevlava/chirpy/internal/delivery/http"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// getOpenAPI fetches the document the way clients do.
+func (s *testServer) getOpenAPI() openAPIDocument {
+	s.t.Helper()
+	rr := s.do("GET", "/api/openapi.json", nil)
+	expectStatus(s.t, rr, http.StatusOK)
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		s.t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	return decodeBody[openAPIDocument](s.t, rr)
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	server := newTestServer(t)
+	doc := server.getOpenAPI()
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want a 3.x document", doc.OpenAPI)
+	}
+
+	routes := httpdelivery.Routes(server.app)
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("route %s is missing from openapi.json", route)
+		}
+	}
+
+	// and the document describes nothing the router does not serve
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			route := strings.ToUpper(method) + " " + path
+			if !slices.Contains(routes, route) {
+				t.Errorf("openapi.json documents %s, which is not a route", route)
+			}
+		}
+	}
+}
+
+func TestOpenAPICaching(t *testing.T) {
+	server := newTestServer(t)
+	rr := server.do("GET", "/api/openapi.json", nil)
+	expectStatus(t, rr, http.StatusOK)
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if rr.Header().Get("Deprecation") != "" {
+		t.Error("the document is served with deprecation headers")
+	}
+
+	rr = server.do("GET", "/api/openapi.json", nil, "If-None-Match", etag)
+	expectStatus(t, rr, http.StatusNotModified)
+}
@@ -0,0 +1,181 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/auth/oidctest"
+	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+var magicLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+func TestMagicLink(t *testing.T) {
+	server := newTestServer(t)
+
+	requestLink := func(t *testing.T, email string) string {
+		t.Helper()
+		rr := server.do("POST", "/api/login/magic", map[string]string{"email": email})
+		expectStatus(t, rr, http.StatusAccepted)
+		msg := server.mailer.last(t)
+		if msg.To != email {
+			t.Fatalf("mail sent to %q, want %q", msg.To, email)
+		}
+		link, err := url.Parse(magicLinkPattern.FindString(msg.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return link.Query().Get("token")
+	}
+
+	t.Run("NewUser", func(t *testing.T) {
+		token := requestLink(t, "saul@bettercall.com")
+		rr := server.do("POST", "/api/login/magic/confirm", map[string]string{"token": token})
+		expectStatus(t, rr, http.StatusOK)
+		if user := decodeBody[app.UserResponse](t, rr); user.Email != "saul@bettercall.com" || user.Token == "" {
+			t.Errorf("user = %+v, want a session for the new account", user)
+		}
+
+		// links are single use
+		rr = server.do("POST", "/api/login/magic/confirm", map[string]string{"token": token})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("ExistingUser", func(t *testing.T) {
+		walter := server.createUser("walter@breakingbad.com")
+		token := requestLink(t, "walter@breakingbad.com")
+		rr := server.do("POST", "/api/login/magic/confirm", map[string]string{"token": token})
+		expectStatus(t, rr, http.StatusOK)
+		if user := decodeBody[app.UserResponse](t, rr); user.ID != walter.ID {
+			t.Errorf("logged in as %s, want %s", user.ID, walter.ID)
+		}
+	})
+	t.Run("InvalidToken", func(t *testing.T) {
+		rr := server.do("POST", "/api/login/magic/confirm", map[string]string{"token": "made-up"})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+}
+
+func TestMagicLinkNeedsMailOutsideDev(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		platform string
+		smtp     string
+		want     int
+	}{
+		{"Production", config.PlatformProduction, "", http.StatusNotFound},
+		{"Staging", config.PlatformStaging, "", http.StatusNotFound},
+		{"ProductionWithSMTP", config.PlatformProduction, "smtp.example.com:587", http.StatusAccepted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := config.Defaults()
+			settings.Platform = tc.platform
+			settings.SMTP.Addr = tc.smtp
+			cfg, err := config.New(&settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newTestServer(t, func(c *config.ApiConfig) {
+				c.MagicLinks = cfg.MagicLinks
+			})
+
+			rr := server.do("POST", "/api/login/magic", map[string]string{"email": "saul@bettercall.com"})
+			expectStatus(t, rr, tc.want)
+			rr = server.do("POST", "/api/login/magic/confirm", map[string]string{"token": "stolen"})
+			if tc.want == http.StatusNotFound {
+				expectStatus(t, rr, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("NotConfigured", func(t *testing.T) {
+		server := newTestServer(t)
+		rr := server.do("GET", "/api/login/oidc", nil)
+		expectStatus(t, rr, http.StatusNotFound)
+	})
+
+	fake := oidctest.NewProvider("chirpy-client", "chirpy-secret")
+	defer fake.Close()
+	server := newTestServer(t, func(cfg *config.ApiConfig) {
+		cfg.OIDCProvider = auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:       fake.Issuer(),
+			ClientID:     "chirpy-client",
+			ClientSecret: "chirpy-secret",
+			RedirectURL:  "http://localhost:8080/api/login/oidc/callback",
+		}, fake.Client())
+	})
+
+	// login follows the redirect to the provider and back to the callback
+	login := func(t *testing.T) *http.Response {
+		t.Helper()
+		rr := server.do("GET", "/api/login/oidc", nil)
+		expectStatus(t, rr, http.StatusFound)
+		code, state, err := fake.Authorize(rr.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookie := rr.Result().Cookies()[0]
+		callback := "/api/login/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+		return server.do("GET", callback, nil, "Cookie", cookie.Name+"="+cookie.Value).Result()
+	}
+
+	t.Run("NewUser", func(t *testing.T) {
+		fake.SetUser(oidctest.User{Subject: "user-1", Email: "saul@bettercall.com", EmailVerified: true})
+		resp := login(t)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("callback status = %d, want 200", resp.StatusCode)
+		}
+
+		// the identity is linked, so a second login finds the same account
+		resp = login(t)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("second callback status = %d, want 200", resp.StatusCode)
+		}
+	})
+	t.Run("LinkVerifiedEmail", func(t *testing.T) {
+		walter := server.createUser("walter@breakingbad.com")
+		fake.SetUser(oidctest.User{Subject: "user-2", Email: "walter@breakingbad.com", EmailVerified: true})
+		resp := login(t)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("callback status = %d, want 200", resp.StatusCode)
+		}
+		var user app.UserResponse
+		err := json.NewDecoder(resp.Body).Decode(&user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.ID != walter.ID {
+			t.Errorf("logged in as %s, want the existing account %s", user.ID, walter.ID)
+		}
+	})
+	t.Run("UnverifiedEmailTaken", func(t *testing.T) {
+		server.createUser("jesse@breakingbad.com")
+		fake.SetUser(oidctest.User{Subject: "user-3", Email: "jesse@breakingbad.com"})
+		resp := login(t)
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("callback status = %d, want 409", resp.StatusCode)
+		}
+	})
+	t.Run("StateMismatch", func(t *testing.T) {
+		rr := server.do("GET", "/api/login/oidc", nil)
+		cookie := rr.Result().Cookies()[0]
+		rr = server.do("GET", "/api/login/oidc/callback?code=x&state=forged", nil, "Cookie", cookie.Name+"="+cookie.Value)
+		expectStatus(t, rr, http.StatusBadRequest)
+	})
+	t.Run("ProviderError", func(t *testing.T) {
+		injected := "<script>alert(1)</script>"
+		rr := server.do("GET", "/api/login/oidc/callback?"+url.Values{"error": {injected}}.Encode(), nil)
+		expectStatus(t, rr, http.StatusUnauthorized)
+		if problem := decodeBody[httputil.Problem](t, rr); strings.Contains(problem.Detail, injected) {
+			t.Errorf("detail = %q, echoes the provider error", problem.Detail)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ApiConfig) {
+		cfg.RateLimits = map[string]ratelimit.Policy{
+			"POST /api/chirps": {Requests: 2, Period: time.Minute},
+		}
+	})
+	walter := server.createUser("walter@breakingbad.com")
+	jesse := server.createUser("jesse@breakingbad.com")
+	chirp := map[string]string{"body": "Say my name"}
+
+	for _, remaining := range []string{"1", "0"} {
+		rr := server.do("POST", "/api/chirps", chirp, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusCreated)
+		if got := rr.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("RateLimit-Remaining = %q, want %q", got, remaining)
+		}
+		if got := rr.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("RateLimit-Limit = %q, want 2", got)
+		}
+	}
+
+	// every API version draws from the same bucket
+	rr := server.do("POST", "/api/v1/chirps", chirp, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusTooManyRequests)
+	if got := rr.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := decodeBody[httputil.Problem](t, rr).Code; got != httputil.CodeTooManyRequests {
+		t.Errorf("code = %q, want %q", got, httputil.CodeTooManyRequests)
+	}
+
+	// buckets are per user, so walter's limit does not hold jesse back
+	rr = server.do("POST", "/api/chirps", chirp, bearer(jesse.Token)...)
+	expectStatus(t, rr, http.StatusCreated)
+
+	// a personal API key counts against the client IP until it has been
+	// accepted once, and shares its owner's bucket from then on
+	rr = server.do("POST", "/api/keys", map[string]any{"name": "bot", "scopes": []string{auth.ScopeChirpsWrite}}, bearer(jesse.Token)...)
+	expectStatus(t, rr, http.StatusCreated)
+	key := decodeBody[app.APIKeyResponse](t, rr).Key
+	for _, remaining := range []string{"1", "0"} {
+		rr = server.do("POST", "/api/chirps", chirp, "Authorization", "ApiKey "+key)
+		expectStatus(t, rr, http.StatusCreated)
+		if got := rr.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("RateLimit-Remaining with jesse's key = %q, want %q", got, remaining)
+		}
+	}
+	rr = server.do("POST", "/api/chirps", chirp, "Authorization", "ApiKey "+key)
+	expectStatus(t, rr, http.StatusTooManyRequests)
+
+	// made-up keys are limited by IP, so rotating them does not help
+	rr = server.do("POST", "/api/chirps", chirp, "Authorization", "ApiKey chirpy_made-up-1")
+	expectStatus(t, rr, http.StatusUnauthorized)
+	rr = server.do("POST", "/api/chirps", chirp, "Authorization", "ApiKey chirpy_made-up-2")
+	expectStatus(t, rr, http.StatusTooManyRequests)
+
+	// routes without a policy are not limited
+	rr = server.do("GET", "/api/chirps", nil)
+	expectStatus(t, rr, http.StatusOK)
+	if got := rr.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("unlimited route has RateLimit-Limit %q", got)
+	}
+}
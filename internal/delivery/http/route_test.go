@@ -4,34 +4,23 @@ package http_test // Use _test package convention
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
-	// Adjust these import paths to match your project structure
 	"github.com/maevlava/chirpy/internal/app"
 	"github.com/maevlava/chirpy/internal/config"
-	httpdelivery "github.com/maevlava/chirpy/internal/delivery/http"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/delivery/httputil"
 )
 
 // TestHealthzMethods specifically checks the method handling for /healthz
 func TestHealthzMethods(t *testing.T) {
 	// --- Setup ---
-	// Create minimal dependencies needed JUST to initialize the router
-	// Use the same Load/NewApplication as your main.go
-	cfg := config.Load() // Assumes Load() exists and returns *config.ApiConfig
-	// Ensure WebStaticDir is set if NewRouter depends on it, even if not used in this test path
-	if cfg.WebStaticDir == "" {
-		cfg.WebStaticDir = "../../../web/static" // Adjust relative path if needed from this test file's location
-	}
-	appInstance := app.NewApplication(cfg)
-
-	// Use the EXACT NewRouter function from your routes.go file
-	// *** IMPORTANT: Ensure the version of NewRouter being tested ***
-	// *** DOES NOT have the metricsMiddleware applied to /healthz ***
-	// *** It should have: mux.Handle("GET /healthz", readinessHandler) ***
-	testRouter := httpdelivery.NewRouter(appInstance)
+	// The full router on an in-memory store, no env vars or database needed
+	testRouter := newTestServer(t).handler
 
 	// --- Test GET Request ---
-	reqGet, err := http.NewRequest("GET", "/healthz", nil)
+	reqGet, err := http.NewRequest("GET", "/api/healthz", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -40,12 +29,12 @@ func TestHealthzMethods(t *testing.T) {
 
 	// Check GET status code
 	if status := rrGet.Code; status != http.StatusOK {
-		t.Errorf("GET /healthz handler returned wrong status code: got %v want %v",
+		t.Errorf("GET /api/healthz handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
 	}
 
 	// --- Test POST Request ---
-	reqPost, err := http.NewRequest("POST", "/healthz", nil)
+	reqPost, err := http.NewRequest("POST", "/api/healthz", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -56,7 +45,113 @@ func TestHealthzMethods(t *testing.T) {
 	expectedStatus := http.StatusMethodNotAllowed
 	if status := rrPost.Code; status != expectedStatus {
 		// Include the response body in the error if it's not 405, might give clues
-		t.Errorf("POST /healthz handler returned wrong status code: got %v want %v\nResponse Body: %s",
+		t.Errorf("POST /api/healthz handler returned wrong status code: got %v want %v\nResponse Body: %s",
 			status, expectedStatus, rrPost.Body.String())
 	}
 }
+
+func TestReadyz(t *testing.T) {
+	server := newTestServer(t)
+
+	rr := server.do("GET", "/api/readyz", nil)
+	expectStatus(t, rr, http.StatusOK)
+	if got := decodeBody[app.ReadinessResponse](t, rr); got.Status != "ok" {
+		t.Errorf("status = %q, want ok", got.Status)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	server := newTestServer(t)
+
+	rr := server.do("GET", "/api/healthz", nil, "X-Request-ID", "req-123")
+	if got := rr.Header().Get("X-Request-ID"); got != "req-123" {
+		t.Errorf("X-Request-ID = %q, want the incoming ID", got)
+	}
+	rr = server.do("GET", "/api/healthz", nil)
+	if rr.Header().Get("X-Request-ID") == "" {
+		t.Error("X-Request-ID is not generated")
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ApiConfig) {
+		cfg.CORS.AllowedOrigins = []string{"https://chirpy.example.com"}
+	})
+
+	rr := server.do("OPTIONS", "/api/chirps", nil,
+		"Origin", "https://chirpy.example.com",
+		"Access-Control-Request-Method", "POST",
+		"Access-Control-Request-Headers", "authorization, content-type",
+	)
+	expectStatus(t, rr, http.StatusNoContent)
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+		t.Errorf("Access-Control-Allow-Headers = %q, want Authorization allowed", got)
+	}
+
+	rr = server.do("GET", "/api/healthz", nil, "Origin", "https://chirpy.example.com")
+	expectStatus(t, rr, http.StatusOK)
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://chirpy.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	rr = server.do("GET", "/api/healthz", nil, "Origin", "https://evil.example.com")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for a foreign origin", got)
+	}
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	server := newTestServer(t)
+
+	for path, want := range map[string]string{
+		"/app":         httputil.WebAppContentSecurityPolicy,
+		"/app/":        httputil.WebAppContentSecurityPolicy,
+		"/api/healthz": httputil.APIContentSecurityPolicy,
+	} {
+		rr := server.do("GET", path, nil)
+		if got := rr.Header().Get("Content-Security-Policy"); got != want {
+			t.Errorf("%s: Content-Security-Policy = %q, want %q", path, got, want)
+		}
+		if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("%s: X-Content-Type-Options = %q", path, got)
+		}
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	rr := server.do("POST", "/api/v1/chirps", map[string]string{"body": "Say my name"}, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusCreated)
+	chirp := decodeBody[database.Chirp](t, rr)
+	for _, name := range []string{"Deprecation", "Sunset", "Link"} {
+		if got := rr.Header().Get(name); got != "" {
+			t.Errorf("v1 response has %s %q", name, got)
+		}
+	}
+
+	// the unversioned paths serve v1 and announce their retirement
+	rr = server.do("GET", "/api/chirps/"+chirp.ID.String(), nil)
+	expectStatus(t, rr, http.StatusOK)
+	if got := decodeBody[database.Chirp](t, rr); got.ID != chirp.ID {
+		t.Errorf("unversioned chirp = %+v, want %+v", got, chirp)
+	}
+	for name, want := range map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Mon, 19 Apr 2027 00:00:00 GMT",
+		"Link":        `</api/v1/chirps/` + chirp.ID.String() + `>; rel="successor-version"`,
+	} {
+		if got := rr.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// paths that match no route are not deprecated aliases of anything
+	rr = server.do("GET", "/api/v2/chirps", nil)
+	expectStatus(t, rr, http.StatusNotFound)
+	for _, name := range []string{"Deprecation", "Sunset", "Link"} {
+		if got := rr.Header().Get(name); got != "" {
+			t.Errorf("404 response has %s %q", name, got)
+		}
+	}
+}
@@ -2,30 +2,111 @@ package http
 
 import (
 	"github.com/maevlava/chirpy/internal/app"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
 	"net/http"
+	"slices"
+	"strings"
 )
 
 func NewRouter(app *app.Application) http.Handler {
 	mux := serveFileServerMux(app)
-	apiMux := serveApiMux(app)
 	adminMux := serveAdminMux(app)
 
-	apiHandler := http.StripPrefix("/api", apiMux)
-	adminHandler := http.StripPrefix("/admin", adminMux)
+	for _, version := range apiVersions {
+		prefix := "/api/" + version.name
+		apiMux := serveApiMux(app, prefix, version.routes(app))
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, apiMux))
+	}
+
+	// the unversioned paths predate versioning and stay v1 until the sunset;
+	// only their routes are marked, so e.g. a 404 for /api/v2/chirps does
+	// not point at /api/v1/v2/chirps
+	deprecatedAt, sunsetAt := app.Config.API.Deprecation()
+	deprecation := httputil.MiddlewareDeprecation(deprecatedAt, sunsetAt, func(path string) string {
+		return "/api/v1" + path
+	})
+	unversioned := serveApiMux(app, "/api", v1Routes(app), deprecation)
+	mux.Handle("/api/", http.StripPrefix("/api", unversioned))
+	instrumentedMux(app, mux, "", "")(openAPIRoute, serveOpenAPI)
 
+	adminHandler := http.StripPrefix("/admin", adminMux)
 	mux.Handle("/admin/", adminHandler)
-	mux.Handle("/api/", apiHandler)
 
-	return mux
+	clientIP := httputil.MiddlewareClientIP(app.Config.Server.TrustedProxyPrefixes())
+	accessLog := httputil.MiddlewareAccessLog(app.Logger)
+	recoverPanics := httputil.MiddlewareRecover(app.Config.Metrics.RecordPanic)
+	securityHeaders := httputil.MiddlewareSecurityHeaders(app.Config.Server.HSTSMaxAge)
+	cors := httputil.MiddlewareCORS(httputil.CORSOptions(app.Config.CORS))
+	return httputil.MiddlewareRequestID(clientIP(accessLog(recoverPanics(securityHeaders(cors(httputil.MiddlewareCompress(mux)))))))
+}
+
+// openAPIRoute serves the OpenAPI document, outside the versions it
+// describes.
+const openAPIRoute = "GET /api/openapi.json"
+
+// routeTable maps a pattern such as "GET /chirps" to its handler.
+type routeTable map[string]http.HandlerFunc
+
+// Routes returns the API and admin routes as clients see them, such as
+// "GET /api/v1/chirps/{chirpId}", sorted. The unversioned aliases of v1
+// are left out.
+func Routes(app *app.Application) []string {
+	routes := []string{openAPIRoute}
+	add := func(prefix string, table routeTable) {
+		for pattern := range table {
+			method, path, _ := strings.Cut(pattern, " ")
+			routes = append(routes, method+" "+prefix+path)
+		}
+	}
+	for _, version := range apiVersions {
+		add("/api/"+version.name, version.routes(app))
+	}
+	add("/admin", adminRoutes(app))
+	slices.Sort(routes)
+	return routes
+}
+
+// apiVersion is an API version mounted under /api/<name>/. A new version
+// starts from a copy of the previous one's routes and replaces the handlers
+// whose request or response shapes change, so clients of older versions
+// keep the shapes they were written against:
+//
+//	func v2Routes(app *app.Application) routeTable {
+//		routes := maps.Clone(v1Routes(app))
+//		routes["GET /chirps"] = app.HandlerGetChirpsV2
+//		return routes
+//	}
+type apiVersion struct {
+	name   string
+	routes func(app *app.Application) routeTable
+}
+
+var apiVersions = []apiVersion{
+	{name: "v1", routes: v1Routes},
 }
+
+// cachePolicies are the Cache-Control values of the routes clients may
+// cache; every other API and admin response is no-store. The chirp list
+// changes all the time, so clients revalidate it with its ETag on every
+// use, while a posted chirp never changes and can only disappear. The web
+// client page is revalidated so deploys show up at once; its assets are
+// not fingerprinted, so they are only cached briefly.
+var cachePolicies = map[string]string{
+	"GET /api/chirps":           "public, no-cache",
+	"GET /api/chirps/{chirpId}": "public, max-age=60",
+	openAPIRoute:                "public, no-cache",
+	"/app":                      "no-cache",
+	"/app/":                     "public, max-age=300",
+}
+
 func serveFileServerMux(app *app.Application) *http.ServeMux {
 	//fileServerPath
 
 	mux := http.NewServeMux()
 
-	metricsMiddleware := app.MiddlewareMetricsInc
+	instrument := app.Config.Metrics.Instrument
 	fileSeverHandler := http.FileServer(http.Dir(app.Config.WebStaticDir))
-	handlerWithMetrics := metricsMiddleware(fileSeverHandler)
+	handlerWithMetrics := instrument("/app/", fileSeverHandler)
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
@@ -34,36 +115,69 @@ func serveFileServerMux(app *app.Application) *http.ServeMux {
 		}
 	})
 
-	webAppHandler := metricsMiddleware(http.HandlerFunc(app.HandlerWebApp))
-	mux.Handle("/app", webAppHandler)
+	webAppHandler := instrument("/app", http.HandlerFunc(app.HandlerWebApp))
+	webAppHandler = httputil.MiddlewareCacheControl(cachePolicies["/app"])(webAppHandler)
+	mux.Handle("/app", httputil.MiddlewareContentSecurityPolicy(httputil.WebAppContentSecurityPolicy, webAppHandler))
 
-	mux.Handle("/app/", http.StripPrefix("/app/", handlerWithMetrics))
+	assetsHandler := httputil.MiddlewareCacheControl(cachePolicies["/app/"])(http.StripPrefix("/app/", handlerWithMetrics))
+	mux.Handle("/app/", httputil.MiddlewareContentSecurityPolicy(httputil.WebAppContentSecurityPolicy, assetsHandler))
 
 	return mux
 }
-func serveApiMux(app *app.Application) *http.ServeMux {
-	// non file server paths
-	metricsMiddleware := app.MiddlewareMetricsInc
-	apiMux := http.NewServeMux()
 
-	apiMux.HandleFunc("GET /chirps", metricsMiddleware(http.HandlerFunc(app.HandlerGetChirps)).ServeHTTP)
-	apiMux.HandleFunc("POST /chirps", metricsMiddleware(http.HandlerFunc(app.HandlerChirps)).ServeHTTP)
-	apiMux.HandleFunc("PUT /users", metricsMiddleware(http.HandlerFunc(app.HandlerUserUpdate)).ServeHTTP)
-	apiMux.HandleFunc("GET /chirps/{chirpId}", metricsMiddleware(http.HandlerFunc(app.HandlerGetChirpByID)).ServeHTTP)
-	apiMux.HandleFunc("DELETE /chirps/{chirpId}", metricsMiddleware(http.HandlerFunc(app.HandlerDeleteChirpByID)).ServeHTTP)
-	apiMux.HandleFunc("GET /healthz", metricsMiddleware(http.HandlerFunc(app.HandlerReadiness)).ServeHTTP)
-	apiMux.HandleFunc("POST /login", metricsMiddleware(http.HandlerFunc(app.HandlerLogin)).ServeHTTP)
-	apiMux.HandleFunc("POST /users", metricsMiddleware(http.HandlerFunc(app.HandlerUsers)).ServeHTTP)
-	apiMux.HandleFunc("POST /refresh", metricsMiddleware(http.HandlerFunc(app.HandlerRefreshToken)).ServeHTTP)
-	apiMux.HandleFunc("POST /revoke", metricsMiddleware(http.HandlerFunc(app.HandlerRevokeToken)).ServeHTTP)
-	apiMux.HandleFunc("POST /polka/webhooks", metricsMiddleware(http.HandlerFunc(app.HandlerPolkaWebhooks)).ServeHTTP)
+// serveApiMux serves routes under prefix, e.g. /api/v1, each wrapped in
+// middleware. Rate limits and cache policies are those of the route under
+// /api, so all versions share them and a client cannot double its limit by
+// switching paths.
+func serveApiMux(app *app.Application, prefix string, routes routeTable, middleware ...func(http.Handler) http.Handler) *http.ServeMux {
+	apiMux := http.NewServeMux()
+	handle := instrumentedMux(app, apiMux, prefix, "/api", middleware...)
+	for pattern, handler := range routes {
+		handle(pattern, handler)
+	}
 	return apiMux
 }
 func serveAdminMux(app *app.Application) *http.ServeMux {
 	adminMux := http.NewServeMux()
+	handle := instrumentedMux(app, adminMux, "/admin", "/admin")
+	for pattern, handler := range adminRoutes(app) {
+		handle(pattern, handler)
+	}
+	return adminMux
+}
 
-	adminMux.HandleFunc("GET /metrics", app.HandlerMetrics)
-	adminMux.HandleFunc("POST /reset", app.HandlerResetUsers)
+func adminRoutes(app *app.Application) routeTable {
+	return routeTable{
+		"GET /metrics":            app.HandlerMetrics,
+		"GET /metrics/prometheus": app.Config.Metrics.Handler().ServeHTTP,
+		"POST /reset":             app.HandlerResetUsers,
+	}
+}
 
-	return adminMux
+// instrumentedMux returns a function registering handlers on mux with
+// request metrics, the configured rate limit, the route's cache policy and
+// any further middleware, outermost last.
+// Metrics use the path as seen by clients, i.e. the pattern's path under
+// prefix, while the policies are looked up by the route under routePrefix,
+// so "GET /chirps/{chirpId}" on the v1 api mux is counted as
+// /api/v1/chirps/{chirpId} and limited by the policy for
+// "GET /api/chirps/{chirpId}".
+func instrumentedMux(app *app.Application, mux *http.ServeMux, prefix, routePrefix string, middleware ...func(http.Handler) http.Handler) func(pattern string, handler http.HandlerFunc) {
+	return func(pattern string, handler http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		route := method + " " + routePrefix + path
+
+		cachePolicy, ok := cachePolicies[route]
+		if !ok {
+			cachePolicy = "no-store"
+		}
+		h := httputil.MiddlewareCacheControl(cachePolicy)(handler)
+		if policy, ok := app.Config.RateLimits[route]; ok {
+			h = httputil.MiddlewareRateLimit(app.Config.RateLimitStore, route, policy, app.RateLimitKey)(h)
+		}
+		for _, mw := range middleware {
+			h = mw(h)
+		}
+		mux.Handle(pattern, app.Config.Metrics.Instrument(prefix+path, h))
+	}
 }
@@ -0,0 +1,156 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/config"
+	"github.com/maevlava/chirpy/internal/database/memdb"
+	httpdelivery "github.com/maevlava/chirpy/internal/delivery/http"
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/mail"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testJWTSecret = "test-jwt-secret"
+	testPolkaKey  = "test-polka-key"
+	testPassword  = "Pollos-Hermanos-1986"
+)
+
+// testServer is the full router wired to an in-memory store, so endpoint
+// tests run without Postgres.
+type testServer struct {
+	t       *testing.T
+	app     *app.Application
+	store   *memdb.Store
+	mailer  *recordingMailer
+	handler http.Handler
+}
+
+// newTestServer builds the router from the default config with test
+// secrets. configure, if given, may adjust the ApiConfig before the
+// application is built.
+func newTestServer(t *testing.T, configure ...func(*config.ApiConfig)) *testServer {
+	t.Helper()
+	settings := config.Defaults()
+	settings.Platform = config.PlatformDev
+	settings.WebStaticDir = "../../../web/static"
+	settings.Auth.JWTSecret = testJWTSecret
+	settings.Auth.PolkaKey = testPolkaKey
+	settings.Auth.BcryptCost = bcrypt.MinCost
+	// tests opt in to rate limits by setting cfg.RateLimits
+	settings.RateLimit.Enabled = false
+
+	cfg, err := config.New(&settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memdb.New()
+	mailer := &recordingMailer{}
+	cfg.DB = store
+	cfg.TokenDenylist = auth.NewStoreDenylist(store, cfg.AccessTokenTTL)
+	cfg.Mailer = mailer
+	cfg.Logger = logging.Discard()
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	application := app.NewApplication(cfg)
+	return &testServer{
+		t:       t,
+		app:     application,
+		store:   store,
+		mailer:  mailer,
+		handler: httpdelivery.NewRouter(application),
+	}
+}
+
+// do sends a request through the router. A non-nil body is encoded as
+// JSON; headers are given as name, value pairs.
+func (s *testServer) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reqBody)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rr := httptest.NewRecorder()
+	s.handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// createUser registers a user with testPassword and logs them in.
+func (s *testServer) createUser(email string) app.UserResponse {
+	s.t.Helper()
+	rr := s.do("POST", "/api/users", map[string]string{"email": email, "password": testPassword})
+	expectStatus(s.t, rr, http.StatusCreated)
+	return s.login(email, testPassword)
+}
+
+func (s *testServer) login(email, password string) app.UserResponse {
+	s.t.Helper()
+	rr := s.do("POST", "/api/login", map[string]string{"email": email, "password": password})
+	expectStatus(s.t, rr, http.StatusOK)
+	return decodeBody[app.UserResponse](s.t, rr)
+}
+
+func bearer(token string) []string {
+	return []string{"Authorization", "Bearer " + token}
+}
+
+func expectStatus(t *testing.T, rr *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rr.Code != want {
+		t.Fatalf("status = %d, want %d\nbody: %s", rr.Code, want, rr.Body.String())
+	}
+}
+
+func decodeBody[T any](t *testing.T, rr *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	err := json.Unmarshal(rr.Body.Bytes(), &v)
+	if err != nil {
+		t.Fatalf("decoding response %q: %v", rr.Body.String(), err)
+	}
+	return v
+}
+
+// recordingMailer keeps sent messages instead of delivering them.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *recordingMailer) last(t *testing.T) mail.Message {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	return m.messages[len(m.messages)-1]
+}
@@ -0,0 +1,99 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+)
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestTwoFactor(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	rr := server.do("POST", "/api/2fa/enroll", nil)
+	expectStatus(t, rr, http.StatusUnauthorized)
+	rr = server.do("POST", "/api/2fa/confirm", map[string]string{"code": "123456"}, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusNotFound)
+
+	rr = server.do("POST", "/api/2fa/enroll", nil, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusCreated)
+	enrollment := decodeBody[app.TwoFactorEnrollResponse](t, rr)
+	if enrollment.Secret == "" || len(enrollment.RecoveryCodes) == 0 {
+		t.Fatalf("enrollment = %+v", enrollment)
+	}
+
+	// login is unchanged until the enrollment is confirmed
+	server.login("walter@breakingbad.com", testPassword)
+
+	rr = server.do("POST", "/api/2fa/confirm", map[string]string{"code": "000000"}, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusUnauthorized)
+	// confirm with the previous step's code, so the login below can use the current one
+	rr = server.do("POST", "/api/2fa/confirm", map[string]string{"code": totpCode(t, enrollment.Secret, time.Now().Add(-30*time.Second))}, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusNoContent)
+	rr = server.do("POST", "/api/2fa/enroll", nil, bearer(walter.Token)...)
+	expectStatus(t, rr, http.StatusConflict)
+
+	challenge := func(t *testing.T) string {
+		t.Helper()
+		rr := server.do("POST", "/api/login", map[string]string{"email": "walter@breakingbad.com", "password": testPassword})
+		expectStatus(t, rr, http.StatusOK)
+		response := decodeBody[app.TwoFactorChallengeResponse](t, rr)
+		if !response.TwoFactorRequired || response.ChallengeToken == "" {
+			t.Fatalf("login response = %+v, want a challenge", response)
+		}
+		return response.ChallengeToken
+	}
+
+	t.Run("Code", func(t *testing.T) {
+		token := challenge(t)
+		code := totpCode(t, enrollment.Secret, time.Now())
+		rr := server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "code": code})
+		expectStatus(t, rr, http.StatusOK)
+		if decodeBody[app.UserResponse](t, rr).Token == "" {
+			t.Error("no access token after second factor")
+		}
+
+		// a code is only accepted once
+		rr = server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "code": code})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("RecoveryCode", func(t *testing.T) {
+		token := challenge(t)
+		recoveryCode := enrollment.RecoveryCodes[0]
+		rr := server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "recovery_code": recoveryCode})
+		expectStatus(t, rr, http.StatusOK)
+		rr = server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "recovery_code": recoveryCode})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("ChallengeIsSingleUse", func(t *testing.T) {
+		token := challenge(t)
+		rr := server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "recovery_code": enrollment.RecoveryCodes[1]})
+		expectStatus(t, rr, http.StatusOK)
+		// another valid code does not mint a second session from it
+		rr = server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "recovery_code": enrollment.RecoveryCodes[2]})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("InvalidChallenge", func(t *testing.T) {
+		rr := server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": walter.Token, "code": "123456"})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("PasswordChangeRevokesChallenge", func(t *testing.T) {
+		token := challenge(t)
+		rr := server.do("PUT", "/api/users", map[string]string{"email": "walter@breakingbad.com", "password": "Blue-Sky-99.1-Percent"}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusOK)
+		rr = server.do("POST", "/api/login/2fa", map[string]string{"challenge_token": token, "recovery_code": enrollment.RecoveryCodes[3]})
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,153 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/app"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/config"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestCreateUser(t *testing.T) {
+	server := newTestServer(t)
+
+	rr := server.do("POST", "/api/users", map[string]string{"email": "walter@breakingbad.com", "password": testPassword})
+	expectStatus(t, rr, http.StatusCreated)
+	user := decodeBody[app.UserResponse](t, rr)
+	if user.Email != "walter@breakingbad.com" || user.Token != "" {
+		t.Errorf("created user = %+v", user)
+	}
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		rr := server.do("POST", "/api/users", map[string]string{"email": "walter@breakingbad.com", "password": testPassword})
+		expectStatus(t, rr, http.StatusConflict)
+	})
+	t.Run("InvalidEmail", func(t *testing.T) {
+		rr := server.do("POST", "/api/users", map[string]string{"email": "walter", "password": testPassword})
+		expectStatus(t, rr, http.StatusBadRequest)
+		problem := decodeBody[httputil.Problem](t, rr)
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != "email" {
+			t.Errorf("errors = %+v, want one for email", problem.Errors)
+		}
+	})
+	t.Run("WeakPassword", func(t *testing.T) {
+		rr := server.do("POST", "/api/users", map[string]string{"email": "jesse@breakingbad.com", "password": "password"})
+		expectStatus(t, rr, http.StatusBadRequest)
+		problem := decodeBody[httputil.Problem](t, rr)
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != "password" {
+			t.Errorf("errors = %+v, want one for password", problem.Errors)
+		}
+	})
+	t.Run("DefaultPolicy", func(t *testing.T) {
+		// an ApiConfig built by hand still gets the default policy
+		server := newTestServer(t, func(cfg *config.ApiConfig) { cfg.PasswordPolicy = auth.PasswordPolicy{} })
+		rr := server.do("POST", "/api/users", map[string]string{"email": "jesse@breakingbad.com", "password": "password"})
+		expectStatus(t, rr, http.StatusBadRequest)
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+	server.createUser("jesse@breakingbad.com")
+
+	update := map[string]string{"email": "heisenberg@breakingbad.com", "password": "Blue-Sky-99.1-Percent"}
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		rr := server.do("PUT", "/api/users", update)
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("EmailTaken", func(t *testing.T) {
+		rr := server.do("PUT", "/api/users", map[string]string{"email": "jesse@breakingbad.com", "password": testPassword}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusConflict)
+	})
+	t.Run("Update", func(t *testing.T) {
+		rr := server.do("PUT", "/api/users", update, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusOK)
+		if got := decodeBody[app.UserResponse](t, rr); got.Email != update["email"] {
+			t.Errorf("email = %q, want %q", got.Email, update["email"])
+		}
+
+		// the new credentials work and existing sessions are revoked
+		server.login(update["email"], update["password"])
+		rr = server.do("POST", "/api/refresh", nil, bearer(walter.RefreshToken)...)
+		expectStatus(t, rr, http.StatusUnauthorized)
+		// even though it was minted within the same second as the change
+		rr = server.do("POST", "/api/chirps", map[string]string{"body": "Say my name"}, bearer(walter.Token)...)
+		expectStatus(t, rr, http.StatusUnauthorized)
+	})
+	t.Run("LoginRightAfterPasswordChange", func(t *testing.T) {
+		jesse := server.login("jesse@breakingbad.com", testPassword)
+		rr := server.do("PUT", "/api/users", map[string]string{"email": "jesse@breakingbad.com", "password": "Yeah-Science-2008"}, bearer(jesse.Token)...)
+		expectStatus(t, rr, http.StatusOK)
+
+		// issued within the second of the revocation, but after it
+		jesse = server.login("jesse@breakingbad.com", "Yeah-Science-2008")
+		rr = server.do("POST", "/api/chirps", map[string]string{"body": "Yeah, science!"}, bearer(jesse.Token)...)
+		expectStatus(t, rr, http.StatusCreated)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	server := newTestServer(t)
+	server.createUser("walter@breakingbad.com")
+
+	for _, tc := range []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"WrongPassword", "walter@breakingbad.com", "Pollos-Hermanos-1987"},
+		{"UnknownEmail", "gus@lospolloshermanos.com", testPassword},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := server.do("POST", "/api/login", map[string]string{"email": tc.email, "password": tc.password})
+			expectStatus(t, rr, http.StatusUnauthorized)
+			if got := decodeBody[httputil.Problem](t, rr).Code; got != "invalid_credentials" {
+				t.Errorf("code = %q, want invalid_credentials", got)
+			}
+		})
+	}
+
+	t.Run("LockedOut", func(t *testing.T) {
+		var rr = server.do("POST", "/api/login", map[string]string{"email": "walter@breakingbad.com", "password": "wrong"})
+		for i := 0; i < 20 && rr.Code != http.StatusTooManyRequests; i++ {
+			rr = server.do("POST", "/api/login", map[string]string{"email": "walter@breakingbad.com", "password": "wrong"})
+		}
+		expectStatus(t, rr, http.StatusTooManyRequests)
+		if rr.Header().Get("Retry-After") == "" {
+			t.Error("Retry-After is not set")
+		}
+		if got := decodeBody[httputil.Problem](t, rr).Code; got != "account_locked" {
+			t.Errorf("code = %q, want account_locked", got)
+		}
+	})
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	server := newTestServer(t)
+	walter := server.createUser("walter@breakingbad.com")
+
+	rr := server.do("POST", "/api/refresh", nil, bearer(walter.RefreshToken)...)
+	expectStatus(t, rr, http.StatusOK)
+	refreshed := decodeBody[app.RefreshTokenResponse](t, rr)
+	if refreshed.Token == "" {
+		t.Fatal("refresh returned no access token")
+	}
+
+	// revoking the access token locks it out of authenticated endpoints
+	rr = server.do("POST", "/api/revoke", nil, bearer(refreshed.Token)...)
+	expectStatus(t, rr, http.StatusNoContent)
+	rr = server.do("GET", "/api/keys", nil, bearer(refreshed.Token)...)
+	expectStatus(t, rr, http.StatusUnauthorized)
+
+	rr = server.do("POST", "/api/revoke", nil, bearer(walter.RefreshToken)...)
+	expectStatus(t, rr, http.StatusNoContent)
+	rr = server.do("POST", "/api/refresh", nil, bearer(walter.RefreshToken)...)
+	expectStatus(t, rr, http.StatusUnauthorized)
+
+	rr = server.do("POST", "/api/refresh", nil)
+	expectStatus(t, rr, http.StatusUnauthorized)
+}
@@ -0,0 +1,77 @@
+package httputil
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+// MiddlewareAccessLog logs one line per request with its status, response
+// size and duration, plus anything handlers added with
+// logging.AddRequestAttrs, such as the user ID. It must run inside
+// MiddlewareRequestID: the request ID is attached to the access log line
+// and to the logger handlers get from logging.FromContext.
+func MiddlewareAccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestLogger := logger.With(slog.String("request_id", RequestID(r.Context())))
+			ctx := logging.WithLogger(r.Context(), requestLogger)
+			ctx, requestAttrs := logging.WithRequestAttrs(ctx)
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", recorder.status()),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			attrs = append(attrs, requestAttrs()...)
+			requestLogger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+		})
+	}
+}
+
+// statusRecorder remembers the status and counts the bytes written through
+// it. Unwrap lets http.ResponseController reach the Flusher and Hijacker of
+// the underlying writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// status is the status sent, which is 200 if the handler wrote nothing.
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
@@ -0,0 +1,78 @@
+package httputil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+func TestMiddlewareAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := logging.New(&out, "info", logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := httputil.MiddlewareRequestID(httputil.MiddlewareAccessLog(logger)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.AddRequestAttrs(r.Context(), slog.String("user_id", "walter"))
+			logging.FromContext(r.Context()).Warn("from handler")
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("short and stout"))
+		}),
+	))
+	req := httptest.NewRequest(http.MethodGet, "/api/teapot", nil)
+	req.Header.Set(httputil.RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var lines []map[string]any
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2:\n%v", len(lines), lines)
+	}
+	if lines[0]["msg"] != "from handler" || lines[0]["request_id"] != "req-1" {
+		t.Errorf("handler log = %v, want it tagged with the request ID", lines[0])
+	}
+
+	access := lines[1]
+	for key, want := range map[string]any{
+		"msg":        "request",
+		"request_id": "req-1",
+		"method":     "GET",
+		"path":       "/api/teapot",
+		"status":     float64(http.StatusTeapot),
+		"bytes":      float64(len("short and stout")),
+		"user_id":    "walter",
+	} {
+		if access[key] != want {
+			t.Errorf("access log %s = %v, want %v", key, access[key], want)
+		}
+	}
+	if _, ok := access["duration"]; !ok {
+		t.Error("access log has no duration")
+	}
+}
+
+func TestLoggingNew(t *testing.T) {
+	for _, tt := range []struct{ level, format string }{
+		{"loud", logging.FormatJSON},
+		{"info", "xml"},
+	} {
+		if _, err := logging.New(&bytes.Buffer{}, tt.level, tt.format); err == nil {
+			t.Errorf("New(%q, %q) succeeded", tt.level, tt.format)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package httputil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// MiddlewareCacheControl sets the Cache-Control header of the responses of
+// next to policy. Problem responses replace it with no-store, so errors are
+// never cached under a route's policy.
+func MiddlewareCacheControl(policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RespondWithJSONETag writes payload as a 200 like RespondWithJSON, tagged
+// with a weak ETag over its encoding. When the request's If-None-Match
+// already names that ETag, only an empty 304 is sent. The ETag is weak
+// because MiddlewareCompress may send the same payload in other encodings.
+func RespondWithJSONETag(w http.ResponseWriter, r *http.Request, payload any) error {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(response)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+	return nil
+}
+
+// etagMatches reports whether the If-None-Match header ifNoneMatch lists
+// etag, comparing weakly as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
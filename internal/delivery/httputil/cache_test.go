@@ -0,0 +1,45 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestRespondWithJSONETag(t *testing.T) {
+	payload := map[string]string{"body": "Say my name"}
+	respond := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		if err := httputil.RespondWithJSONETag(rec, req, payload); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	first := respond("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || len(etag) < 5 || etag[:3] != `W/"` {
+		t.Fatalf("first response = %d with ETag %q, want 200 with a weak ETag", first.Code, etag)
+	}
+
+	strong := etag[2:]
+	for _, ifNoneMatch := range []string{etag, strong, `"other", ` + etag, "*"} {
+		rec := respond(ifNoneMatch)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: %d with %d bytes, want an empty 304", ifNoneMatch, rec.Code, rec.Body.Len())
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 without the ETag", ifNoneMatch)
+		}
+	}
+
+	if rec := respond(`W/"stale"`); rec.Code != http.StatusOK {
+		t.Errorf("stale ETag: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package httputil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// MiddlewareClientIP resolves the address of the client behind any trusted
+// reverse proxies and stores it for ClientIP. Forwarding headers are only
+// believed when the connection comes from a trusted proxy, and are read
+// from the right, skipping further trusted hops, since clients can put
+// anything on the left. Forwarded (RFC 7239) wins over X-Forwarded-For.
+func MiddlewareClientIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	trusted := func(addr netip.Addr) bool {
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientIP := remoteIP(r)
+			addr, err := netip.ParseAddr(clientIP)
+			if err == nil && trusted(addr) {
+				hops := forwardedFor(r.Header)
+				for i := len(hops) - 1; i >= 0; i-- {
+					hop, err := netip.ParseAddr(hops[i])
+					if err != nil {
+						// an obfuscated or unknown hop ends the chain we can trust
+						break
+					}
+					clientIP = hop.Unmap().String()
+					if !trusted(hop) {
+						break
+					}
+				}
+			}
+			ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP returns the client address resolved by MiddlewareClientIP, or
+// the host part of the request's remote address without it.
+func ClientIP(r *http.Request) string {
+	if clientIP, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return clientIP
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the addresses of the hops a request was forwarded
+// for, the client first, without ports.
+func forwardedFor(header http.Header) []string {
+	var hops []string
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		for _, value := range values {
+			for _, element := range strings.Split(value, ",") {
+				hop := ""
+				for _, pair := range strings.Split(element, ";") {
+					name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if strings.EqualFold(name, "for") {
+						hop = stripPort(strings.Trim(value, `"`))
+					}
+				}
+				hops = append(hops, hop)
+			}
+		}
+		return hops
+	}
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, stripPort(strings.TrimSpace(hop)))
+		}
+	}
+	return hops
+}
+
+// stripPort removes the port from "192.0.2.1:4711" and "[2001:db8::1]:4711"
+// and the brackets from "[2001:db8::1]".
+func stripPort(hop string) string {
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]")
+}
@@ -0,0 +1,46 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestMiddlewareClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::1/128")}
+
+	for _, tt := range []struct {
+		name       string
+		remoteAddr string
+		header     map[string]string
+		want       string
+	}{
+		{"direct", "192.0.2.1:4711", nil, "192.0.2.1"},
+		{"untrusted peer cannot claim an address", "192.0.2.1:4711", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "192.0.2.1"},
+		{"trusted proxy", "10.0.0.2:4711", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed left entries are ignored", "10.0.0.2:4711", map[string]string{"X-Forwarded-For": "203.0.113.9, 198.51.100.7, 10.0.0.3"}, "198.51.100.7"},
+		{"Forwarded wins", "10.0.0.2:4711", map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https`, "X-Forwarded-For": "198.51.100.7"}, "2001:db8::7"},
+		{"obfuscated hop", "10.0.0.2:4711", map[string]string{"Forwarded": "for=198.51.100.7, for=_hidden"}, "10.0.0.2"},
+		{"trusted IPv6 proxy", "[2001:db8::1]:4711", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"only proxies", "10.0.0.2:4711", map[string]string{"X-Forwarded-For": "10.0.0.3"}, "10.0.0.3"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := httputil.MiddlewareClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = httputil.ClientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
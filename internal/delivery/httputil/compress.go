@@ -0,0 +1,231 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest response worth compressing; below it the
+// encoding overhead eats the savings.
+const compressMinSize = 1024
+
+// encoder is implemented by both gzip.Writer and brotli.Writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encoderPools keep encoders, which allocate large windows, across
+// responses. Encodings are listed in order of preference.
+var encoderPools = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }}},
+	{"gzip", &sync.Pool{New: func() any { return gzip.NewWriter(nil) }}},
+}
+
+// MiddlewareCompress compresses responses with brotli or gzip, whichever
+// the client's Accept-Encoding prefers, favouring brotli on a tie.
+// Responses that are small, already encoded, partial or of a type that does
+// not compress, such as images, are sent as they are.
+func MiddlewareCompress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding < 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		next.ServeHTTP(cw, r)
+		// not deferred: after a panic the buffered response must be dropped
+		// so MiddlewareRecover can still send its 500
+		cw.Close()
+	})
+}
+
+// negotiateEncoding returns the index in encoderPools of the encoding to
+// use for acceptEncoding, or -1 for none.
+func negotiateEncoding(acceptEncoding string) int {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := -1, 0.0
+	for i, enc := range encoderPools {
+		q, ok := weights[enc.name]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the response until it knows whether
+// compressing it is worthwhile, since Content-Encoding has to be decided
+// before the header is sent.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding int
+	code     int
+	buf      []byte
+	started  bool
+	encoder  encoder
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.started {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code < http.StatusOK {
+		// informational responses go straight out
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	c.code = code
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		c.start(false)
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if !c.started {
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+		c.buf = append(c.buf, b...)
+		if len(c.buf) >= compressMinSize {
+			if err := c.start(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if c.encoder != nil {
+		return c.encoder.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far, compressed if the response is
+// eligible, for handlers that stream.
+func (c *compressWriter) Flush() {
+	if !c.started {
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+		c.start(true)
+	}
+	if c.encoder != nil {
+		c.encoder.Flush()
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Close sends a response that never reached compressMinSize and finishes
+// the compressed stream otherwise.
+func (c *compressWriter) Close() error {
+	if !c.started {
+		if c.code == 0 {
+			// the handler wrote nothing; net/http sends its empty 200
+			return nil
+		}
+		return c.start(false)
+	}
+	if c.encoder == nil {
+		return nil
+	}
+	err := c.encoder.Close()
+	c.encoder.Reset(nil)
+	encoderPools[c.encoding].pool.Put(c.encoder)
+	c.encoder = nil
+	return err
+}
+
+// start sends the header, choosing the encoding, and the buffered body.
+func (c *compressWriter) start(compress bool) error {
+	c.started = true
+	header := c.Header()
+	if compress && c.compressible(header) {
+		enc := encoderPools[c.encoding]
+		c.encoder = enc.pool.Get().(encoder)
+		c.encoder.Reset(c.ResponseWriter)
+		header.Set("Content-Encoding", enc.name)
+		header.Del("Content-Length")
+		// the compressed bytes differ, so a strong validator no longer holds
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	c.ResponseWriter.WriteHeader(c.code)
+
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if c.encoder != nil {
+		_, err = c.encoder.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (c *compressWriter) compressible(header http.Header) bool {
+	if c.code == http.StatusPartialContent || header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(c.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml":
+		return true
+	}
+	return false
+}
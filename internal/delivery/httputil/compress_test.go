@@ -0,0 +1,119 @@
+package httputil_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestMiddlewareCompress(t *testing.T) {
+	large := strings.Repeat(`{"body":"I'm the one who knocks"},`, 100)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{"brotli preferred", "gzip, deflate, br", "application/json", large, "br"},
+		{"gzip by weight", "br;q=0.5, gzip", "application/json", large, "gzip"},
+		{"brotli refused", "br;q=0, *", "application/json", large, "gzip"},
+		{"identity only", "identity", "application/json", large, ""},
+		{"no header", "", "application/json", large, ""},
+		{"small response", "gzip", "application/json", `{"ok":true}`, ""},
+		{"problem json", "gzip", "application/problem+json", large, "gzip"},
+		{"image", "gzip", "image/png", large, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := httputil.MiddlewareCompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				// written in pieces to cross the size threshold mid-response
+				for chunk := range strings.SplitSeq(tt.body, ",") {
+					io.WriteString(w, chunk+",")
+				}
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q", got)
+			}
+			if got := decompress(t, tt.wantEncoding, rec.Body); got != tt.body+"," {
+				t.Errorf("body = %.60q..., want the handler's", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCompressPassesThrough(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    int
+	}{
+		{"already encoded", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			io.WriteString(w, large)
+		}, http.StatusOK},
+		{"partial content", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, large)
+		}, http.StatusPartialContent},
+		{"not modified", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app/assets/logo.png", nil)
+			req.Header.Set("Accept-Encoding", "br, gzip")
+			rec := httptest.NewRecorder()
+			httputil.MiddlewareCompress(tt.handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d", rec.Code, tt.code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got == "br" {
+				t.Errorf("Content-Encoding = %q, want the response untouched", got)
+			}
+			if tt.code != http.StatusNotModified && rec.Body.String() != large {
+				t.Errorf("body was modified")
+			}
+		})
+	}
+}
+
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader = body
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case "br":
+		r = brotli.NewReader(body)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,120 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestMiddlewareCORS(t *testing.T) {
+	opts := httputil.CORSOptions{
+		AllowedOrigins:   []string{"https://chirpy.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	served := false
+	handler := httputil.MiddlewareCORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		served  bool
+		headers map[string]string
+	}{
+		{
+			name:   "Preflight",
+			method: http.MethodOptions,
+			origin: "https://chirpy.example.com",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://chirpy.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:   "PreflightOtherOrigin",
+			method: http.MethodOptions,
+			origin: "https://evil.example.com",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:   "Request",
+			method: http.MethodGet,
+			origin: "https://chirpy.example.com",
+			served: true,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://chirpy.example.com",
+				"Access-Control-Expose-Headers": "X-Request-ID",
+				"Access-Control-Allow-Methods":  "",
+			},
+		},
+		{
+			name:    "RequestOtherOrigin",
+			method:  http.MethodGet,
+			origin:  "https://evil.example.com",
+			served:  true,
+			headers: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:    "SameOrigin",
+			method:  http.MethodGet,
+			served:  true,
+			headers: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = false
+			req := httptest.NewRequest(tt.method, "/api/chirps", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if served != tt.served {
+				t.Errorf("handler served = %v, want %v", served, tt.served)
+			}
+			if !tt.served && rec.Code != http.StatusNoContent {
+				t.Errorf("preflight status = %d, want 204", rec.Code)
+			}
+			for name, want := range tt.headers {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if got := rec.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+				t.Errorf("Vary = %q, want Origin first", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCORSAnyOrigin(t *testing.T) {
+	handler := httputil.MiddlewareCORS(httputil.CORSOptions{AllowedOrigins: []string{"*"}})(http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
@@ -0,0 +1,118 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxBodyBytes caps request bodies read by DecodeJSON. Chirps are at most
+// 200 characters, so 1 MiB leaves plenty of room for every endpoint.
+const MaxBodyBytes = 1 << 20
+
+// Validator is implemented by request structs that check their own fields
+// after decoding. Validate returns nil when the request is valid.
+type Validator interface {
+	Validate() []FieldError
+}
+
+// DecodeJSON strictly decodes the request body into a T: the body must be a
+// single application/json object of at most MaxBodyBytes without unknown
+// fields. If T implements Validator it is validated as well. Errors are
+// *Problem values ready to be written with RespondWithDecodeError.
+func DecodeJSON[T any](w http.ResponseWriter, r *http.Request) (T, error) {
+	return decodeJSON[T](w, r, true)
+}
+
+// DecodeJSONLenient is DecodeJSON for payloads we do not control, such as
+// webhooks: fields unknown to T are ignored, since senders add fields
+// without notice.
+func DecodeJSONLenient[T any](w http.ResponseWriter, r *http.Request) (T, error) {
+	return decodeJSON[T](w, r, false)
+}
+
+func decodeJSON[T any](w http.ResponseWriter, r *http.Request, strict bool) (T, error) {
+	var value T
+
+	// ParseMediaType drops parameters, so a charset is accepted
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return value, NewProblem(http.StatusUnsupportedMediaType, "", "Content-Type must be application/json")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	err = decoder.Decode(&value)
+	if err != nil {
+		return value, decodeProblem(err)
+	}
+	if decoder.Decode(&struct{}{}) != io.EOF {
+		return value, NewProblem(http.StatusBadRequest, "", "Request body must contain a single JSON object")
+	}
+
+	if validator, ok := any(&value).(Validator); ok {
+		if fieldErrors := validator.Validate(); len(fieldErrors) > 0 {
+			problem := NewProblem(http.StatusBadRequest, CodeValidation, "Request validation failed")
+			problem.Errors = fieldErrors
+			return value, problem
+		}
+	}
+	return value, nil
+}
+
+// RespondWithDecodeError writes an error returned by DecodeJSON.
+func RespondWithDecodeError(w http.ResponseWriter, err error) error {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return RespondWithProblem(w, problem)
+	}
+	return RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+}
+
+// decodeProblem turns json decoding errors into messages that point at the
+// offending field without echoing decoder internals.
+func decodeProblem(err error) *Problem {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return NewProblem(http.StatusRequestEntityTooLarge, "", fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return NewProblem(http.StatusBadRequest, "", "Request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		return NewProblem(http.StatusBadRequest, "", "Request body must not be empty")
+	case errors.As(err, &typeErr):
+		problem := NewProblem(http.StatusBadRequest, CodeValidation, "Request validation failed")
+		problem.Errors = []FieldError{{
+			Field:   typeErr.Field,
+			Code:    "invalid_type",
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}}
+		return problem
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		problem := NewProblem(http.StatusBadRequest, CodeValidation, "Request validation failed")
+		problem.Errors = []FieldError{{
+			Field:   field,
+			Code:    "unknown_field",
+			Message: "is not allowed",
+		}}
+		return problem
+	}
+	return NewProblem(http.StatusBadRequest, "", "Invalid request body")
+}
+
+// Required returns a field error when value is empty, for use in Validate.
+func Required(field, value string) []FieldError {
+	if strings.TrimSpace(value) == "" {
+		return []FieldError{{Field: field, Code: "required", Message: "is required"}}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package httputil_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+type greeting struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (g *greeting) Validate() []httputil.FieldError {
+	return httputil.Required("name", g.Name)
+}
+
+func newJSONRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return req
+}
+
+func TestDecodeJSON(t *testing.T) {
+	got, err := httputil.DecodeJSON[greeting](httptest.NewRecorder(), newJSONRequest(`{"name":"chirpy","count":2}`))
+	if err != nil {
+		t.Fatalf("DecodeJSON() error = %v", err)
+	}
+	if got.Name != "chirpy" || got.Count != 2 {
+		t.Errorf("DecodeJSON() = %+v", got)
+	}
+}
+
+func TestDecodeJSONLenient(t *testing.T) {
+	got, err := httputil.DecodeJSONLenient[greeting](httptest.NewRecorder(), newJSONRequest(`{"name":"chirpy","admin":true}`))
+	if err != nil {
+		t.Fatalf("DecodeJSONLenient() error = %v", err)
+	}
+	if got.Name != "chirpy" {
+		t.Errorf("DecodeJSONLenient() = %+v", got)
+	}
+
+	_, err = httputil.DecodeJSONLenient[greeting](httptest.NewRecorder(), newJSONRequest(`{"admin":true}`))
+	if err == nil {
+		t.Error("DecodeJSONLenient() without name error = nil, want validation error")
+	}
+}
+
+func TestDecodeJSONRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		field  string
+	}{
+		{"empty body", newJSONRequest(""), http.StatusBadRequest, ""},
+		{"malformed", newJSONRequest(`{"name":`), http.StatusBadRequest, ""},
+		{"trailing data", newJSONRequest(`{"name":"a"}{"name":"b"}`), http.StatusBadRequest, ""},
+		{"unknown field", newJSONRequest(`{"name":"a","admin":true}`), http.StatusBadRequest, "admin"},
+		{"wrong type", newJSONRequest(`{"name":"a","count":"two"}`), http.StatusBadRequest, "count"},
+		{"failed validation", newJSONRequest(`{"name":" "}`), http.StatusBadRequest, "name"},
+		{"too large", newJSONRequest(`{"name":"` + strings.Repeat("a", httputil.MaxBodyBytes) + `"}`), http.StatusRequestEntityTooLarge, ""},
+		{"missing content type", httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"a"}`)), http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := httputil.DecodeJSON[greeting](httptest.NewRecorder(), tt.req)
+			var problem *httputil.Problem
+			if !errors.As(err, &problem) {
+				t.Fatalf("DecodeJSON() error = %v, want *Problem", err)
+			}
+			if problem.Status != tt.status {
+				t.Errorf("status = %d, want %d", problem.Status, tt.status)
+			}
+			if tt.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field) {
+				t.Errorf("errors = %+v, want field %q", problem.Errors, tt.field)
+			}
+		})
+	}
+}
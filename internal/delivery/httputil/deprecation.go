@@ -0,0 +1,31 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MiddlewareDeprecation marks the responses of next as deprecated since
+// deprecatedAt with the Deprecation header of RFC 9745, announces their
+// removal at sunsetAt, unless zero, with the Sunset header of RFC 8594, and
+// links the successor of each request's path, e.g. the same route in a
+// newer API version.
+func MiddlewareDeprecation(deprecatedAt, sunsetAt time.Time, successor func(path string) string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunset := ""
+	if !sunsetAt.IsZero() {
+		sunset = sunsetAt.UTC().Format(http.TimeFormat)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("Deprecation", deprecation)
+			if sunset != "" {
+				header.Set("Sunset", sunset)
+			}
+			header.Add("Link", "<"+successor(r.URL.Path)+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestMiddlewareDeprecation(t *testing.T) {
+	deprecatedAt := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	successor := func(path string) string {
+		return "/api/v1" + strings.TrimPrefix(path, "/api")
+	}
+
+	for _, tt := range []struct {
+		name       string
+		sunsetAt   time.Time
+		wantSunset string
+	}{
+		{"with sunset", time.Date(2027, 4, 19, 0, 0, 0, 0, time.FixedZone("CEST", 2*3600)), "Sun, 18 Apr 2027 22:00:00 GMT"},
+		{"without sunset", time.Time{}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := httputil.MiddlewareDeprecation(deprecatedAt, tt.sunsetAt, successor)(http.NotFoundHandler())
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/chirps?sort=desc", nil))
+
+			for name, want := range map[string]string{
+				"Deprecation": "@1792368000",
+				"Sunset":      tt.wantSunset,
+				"Link":        `</api/v1/chirps>; rel="successor-version"`,
+			} {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -10,9 +10,7 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) error
 	if err != nil {
 		return err
 	}
-	// cors and json
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	w.WriteHeader(code)
 	w.Write(response)
@@ -20,6 +18,8 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) error
 	return nil
 }
 
+// RespondWithError writes msg as the detail of a problem+json response whose
+// code is derived from the status.
 func RespondWithError(w http.ResponseWriter, code int, msg string) error {
-	return RespondWithJSON(w, code, map[string]string{"error": msg})
+	return RespondWithCode(w, code, "", msg)
 }
@@ -0,0 +1,146 @@
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Stable machine-readable error codes. Clients should branch on these rather
+// than on the human readable detail, which may change.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnsupportedMedia = "unsupported_media_type"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "service_unavailable"
+)
+
+// Problem is an RFC 7807 problem details object, extended with a stable
+// error code, the request ID and per-field validation errors.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// NewProblem builds a problem for status with the given code and detail. An
+// empty code is derived from the status.
+func NewProblem(status int, code, detail string) *Problem {
+	if code == "" {
+		code = codeForStatus(status)
+	}
+	return &Problem{
+		Type:   "urn:chirpy:problem:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func RespondWithProblem(w http.ResponseWriter, problem *Problem) error {
+	if problem.RequestID == "" {
+		problem.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	response, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(problem.Status)
+	w.Write(response)
+	return nil
+}
+
+func RespondWithCode(w http.ResponseWriter, status int, code, msg string) error {
+	return RespondWithProblem(w, NewProblem(status, code, msg))
+}
+
+func RespondWithValidationErrors(w http.ResponseWriter, fieldErrors []FieldError) error {
+	problem := NewProblem(http.StatusBadRequest, CodeValidation, "Request validation failed")
+	problem.Errors = fieldErrors
+	return RespondWithProblem(w, problem)
+}
+
+// RespondWithDBError is the single place where storage errors become HTTP
+// responses: a missing row is a 404, a duplicate a 409, and anything else a
+// 500 whose driver message is logged but never sent to the client.
+func RespondWithDBError(w http.ResponseWriter, r *http.Request, err error) error {
+	var problem *Problem
+	switch {
+	case database.IsNotFound(err):
+		problem = NewProblem(http.StatusNotFound, CodeNotFound, "Resource not found")
+	case database.IsUniqueViolation(err):
+		problem = NewProblem(http.StatusConflict, CodeConflict, "Resource already exists")
+	default:
+		return RespondWithInternalError(w, r, err)
+	}
+	return RespondWithProblem(w, problem)
+}
+
+// RespondWithInternalError logs err with the request's logger and answers
+// with a generic 500.
+func RespondWithInternalError(w http.ResponseWriter, r *http.Request, err error) error {
+	logging.FromContext(r.Context()).Error("internal server error", "err", err)
+	return RespondWithCode(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
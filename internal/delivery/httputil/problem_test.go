@@ -0,0 +1,109 @@
+package httputil_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) httputil.Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != httputil.ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, httputil.ProblemContentType)
+	}
+	problem := httputil.Problem{}
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decoding problem: %v", err)
+	}
+	return problem
+}
+
+func TestRespondWithErrorDerivesCode(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(httputil.RequestIDHeader, "req-1")
+	httputil.RespondWithError(rec, http.StatusNotFound, "Chirp not found")
+
+	problem := decodeProblem(t, rec)
+	if rec.Code != http.StatusNotFound || problem.Status != http.StatusNotFound {
+		t.Errorf("status = %d/%d, want 404", rec.Code, problem.Status)
+	}
+	if problem.Code != httputil.CodeNotFound || problem.Type != "urn:chirpy:problem:not_found" {
+		t.Errorf("code = %q type = %q", problem.Code, problem.Type)
+	}
+	if problem.Detail != "Chirp not found" || problem.Title != "Not Found" {
+		t.Errorf("detail = %q title = %q", problem.Detail, problem.Title)
+	}
+	if problem.RequestID != "req-1" {
+		t.Errorf("request_id = %q, want req-1", problem.RequestID)
+	}
+}
+
+func TestRespondWithValidationErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	httputil.RespondWithValidationErrors(rec, []httputil.FieldError{{Field: "password", Code: "too_short", Message: "password is too short"}})
+
+	problem := decodeProblem(t, rec)
+	if rec.Code != http.StatusBadRequest || problem.Code != httputil.CodeValidation {
+		t.Fatalf("got %d %q", rec.Code, problem.Code)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "password" || problem.Errors[0].Code != "too_short" {
+		t.Errorf("errors = %+v", problem.Errors)
+	}
+}
+
+func TestRespondWithDBError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"no rows", fmt.Errorf("get chirp: %w", sql.ErrNoRows), http.StatusNotFound, httputil.CodeNotFound},
+		{"unique violation", &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "users_email_key"`}, http.StatusConflict, httputil.CodeConflict},
+		{"other", errors.New("pq: connection refused"), http.StatusInternalServerError, httputil.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/chirps", nil)
+			httputil.RespondWithDBError(rec, req, tt.err)
+
+			problem := decodeProblem(t, rec)
+			if rec.Code != tt.status || problem.Code != tt.code {
+				t.Errorf("got %d %q, want %d %q", rec.Code, problem.Code, tt.status, tt.code)
+			}
+			if strings.Contains(problem.Detail, "pq:") || strings.Contains(problem.Detail, "users_email_key") {
+				t.Errorf("driver error leaked to client: %q", problem.Detail)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	var seen string
+	handler := httputil.MiddlewareRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = httputil.RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(httputil.RequestIDHeader, "client-id")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if seen != "client-id" || rec.Header().Get(httputil.RequestIDHeader) != "client-id" {
+		t.Errorf("client request ID not propagated: ctx %q header %q", seen, rec.Header().Get(httputil.RequestIDHeader))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if seen == "" || rec.Header().Get(httputil.RequestIDHeader) != seen {
+		t.Errorf("generated request ID missing: ctx %q header %q", seen, rec.Header().Get(httputil.RequestIDHeader))
+	}
+}
@@ -0,0 +1,48 @@
+package httputil
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/ratelimit"
+)
+
+// MiddlewareRateLimit limits requests to route, a pattern such as
+// "POST /api/chirps", per client as identified by key. Every response
+// carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers of the IETF draft; refused requests get a 429 with Retry-After.
+// When the store fails the request is let through: a broken limiter
+// should not take the API down with it.
+func MiddlewareRateLimit(store ratelimit.Store, route string, policy ratelimit.Policy, key func(*http.Request) string) func(http.Handler) http.Handler {
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Requests, int(policy.Period.Seconds()))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(r.Context(), route+" "+key(r), policy)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter unavailable", "route", route, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", policyHeader)
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				RespondWithCode(w, http.StatusTooManyRequests, CodeTooManyRequests, "Rate limit exceeded, try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,42 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+// MiddlewareRecover turns a panic in next into a 500 problem response
+// instead of a dropped connection, logs it with the stack trace and calls
+// onPanic, if set, e.g. to count it. It must run inside MiddlewareAccessLog
+// so the log line carries the request ID and the access log sees the 500.
+//
+// http.ErrAbortHandler is re-raised: it is how a handler asks net/http to
+// abort the response.
+func MiddlewareRecover(onPanic func()) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				logging.FromContext(r.Context()).Error("panic serving request",
+					"panic", fmt.Sprint(recovered),
+					"stack", string(debug.Stack()),
+				)
+				if onPanic != nil {
+					onPanic()
+				}
+				RespondWithCode(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package httputil_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+func TestMiddlewareRecover(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := logging.New(&out, "info", logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panics := 0
+	recoverPanics := httputil.MiddlewareRecover(func() { panics++ })
+	handler := httputil.MiddlewareRequestID(httputil.MiddlewareAccessLog(logger)(recoverPanics(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("tread lightly")
+		}),
+	)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set(httputil.RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if problem := decodeProblem(t, rec); problem.Code != httputil.CodeInternal || problem.RequestID != "req-1" {
+		t.Errorf("problem = %+v", problem)
+	}
+	if panics != 1 {
+		t.Errorf("onPanic called %d times, want 1", panics)
+	}
+	logged := out.String()
+	for _, want := range []string{`"panic":"tread lightly"`, `"request_id":"req-1"`, "recover_test.go", `"status":500`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log does not contain %s:\n%s", want, logged)
+		}
+	}
+}
+
+func TestMiddlewareRecoverReraisesAbort(t *testing.T) {
+	handler := httputil.MiddlewareRecover(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,69 @@
+package httputil
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content security policies. The API only returns data, so its responses
+// may load nothing; the web client may load its own scripts, styles and
+// images. Neither may be framed.
+const (
+	APIContentSecurityPolicy    = "default-src 'none'; frame-ancestors 'none'"
+	WebAppContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+)
+
+// MiddlewareSecurityHeaders sets the headers hardening every response
+// against sniffing, framing and referrer leaks, with the API content
+// security policy; the web client routes replace it using
+// MiddlewareContentSecurityPolicy. HSTS is sent on TLS requests when
+// hstsMaxAge is positive; browsers ignore it over plain HTTP.
+func MiddlewareSecurityHeaders(hstsMaxAge time.Duration) func(http.Handler) http.Handler {
+	hsts := "max-age=" + strconv.Itoa(int(hstsMaxAge.Seconds())) + "; includeSubDomains"
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", "DENY")
+			header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			header.Set("Content-Security-Policy", APIContentSecurityPolicy)
+			if r.TLS != nil && hstsMaxAge > 0 {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MiddlewareContentSecurityPolicy replaces the content security policy of
+// the responses of next.
+func MiddlewareContentSecurityPolicy(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", policy)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RedirectToHTTPS permanently redirects every request to the same URL over
+// HTTPS on httpsPort. It is served on the plain HTTP port when the server
+// terminates TLS itself.
+func RedirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// no port, possibly a bracketed IPv6 address
+			host = strings.Trim(r.Host, "[]")
+		}
+		switch {
+		case httpsPort != 443:
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,75 @@
+package httputil_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httputil "github.com/maevlava/chirpy/internal/delivery/httputil"
+)
+
+func TestMiddlewareSecurityHeaders(t *testing.T) {
+	handler := httputil.MiddlewareSecurityHeaders(24 * time.Hour)(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Content-Security-Policy":   httputil.APIContentSecurityPolicy,
+		"Strict-Transport-Security": "",
+	} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=86400; includeSubDomains" {
+		t.Errorf("Strict-Transport-Security over TLS = %q", got)
+	}
+}
+
+func TestMiddlewareContentSecurityPolicy(t *testing.T) {
+	webApp := httputil.MiddlewareContentSecurityPolicy(httputil.WebAppContentSecurityPolicy, http.NotFoundHandler())
+	handler := httputil.MiddlewareSecurityHeaders(0)(webApp)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app", nil))
+	if got := rec.Header().Get("Content-Security-Policy"); got != httputil.WebAppContentSecurityPolicy {
+		t.Errorf("Content-Security-Policy = %q, want the web app policy", got)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"chirpy.example.com", 443, "https://chirpy.example.com/api/chirps?sort=desc"},
+		{"chirpy.example.com:80", 443, "https://chirpy.example.com/api/chirps?sort=desc"},
+		{"localhost:8080", 8443, "https://localhost:8443/api/chirps?sort=desc"},
+		{"[::1]:8080", 8443, "https://[::1]:8443/api/chirps?sort=desc"},
+		{"[::1]", 443, "https://[::1]/api/chirps?sort=desc"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps?sort=desc", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		httputil.RedirectToHTTPS(tt.port).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: status = %d, want 308", tt.host, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s: Location = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
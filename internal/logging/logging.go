@@ -0,0 +1,92 @@
+// Package logging builds the structured logger and carries the request
+// scoped logger, tagged with the request ID, through context.Context so
+// handlers and services log lines that can be joined with the access log.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Output formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns a logger writing to w at the given level ("debug", "info",
+// "warn" or "error") in the given format.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(level))
+	if err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("log format must be %q or %q, got %q", FormatJSON, FormatText, format)
+}
+
+// Discard returns a logger that drops everything, for tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+type loggerKey struct{}
+
+type attrsKey struct{}
+
+// requestAttrs collects attributes added while a request is served, to be
+// logged on its access log line.
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithLogger returns a context carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestAttrs returns a context in which AddRequestAttrs collects
+// attributes, and a function returning those collected so far.
+func WithRequestAttrs(ctx context.Context) (context.Context, func() []slog.Attr) {
+	collected := &requestAttrs{}
+	return context.WithValue(ctx, attrsKey{}, collected), func() []slog.Attr {
+		collected.mu.Lock()
+		defer collected.mu.Unlock()
+		return slices.Clone(collected.attrs)
+	}
+}
+
+// AddRequestAttrs records attributes, such as the authenticated user, for
+// the access log line of the request ctx belongs to. It does nothing
+// outside a request.
+func AddRequestAttrs(ctx context.Context, attrs ...slog.Attr) {
+	collected, ok := ctx.Value(attrsKey{}).(*requestAttrs)
+	if !ok {
+		return
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	collected.attrs = append(collected.attrs, attrs...)
+}
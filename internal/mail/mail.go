@@ -0,0 +1,87 @@
+// Package mail sends transactional email such as magic login links.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message or reports why it could not.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them, for when no
+// SMTP server is configured. Bodies may carry secrets such as login links,
+// so they are only logged with IncludeBody, which is meant for local
+// development where the log is the only way to read them.
+type LogMailer struct {
+	IncludeBody bool
+}
+
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	attrs := []any{"to", msg.To, "subject", msg.Subject}
+	if m.IncludeBody {
+		attrs = append(attrs, "body", msg.Body)
+	}
+	logging.FromContext(ctx).Info("mail", attrs...)
+	return nil
+}
+
+// SMTPMailer sends plain text messages through an SMTP relay.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("mail header contains a line break")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address %q: %v", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	// net/smtp has no context support, so run it aside and stop waiting on cancel
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(body))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,43 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maevlava/chirpy/internal/logging"
+	"github.com/maevlava/chirpy/internal/mail"
+)
+
+func TestLogMailer(t *testing.T) {
+	msg := mail.Message{
+		To:      "saul@bettercall.com",
+		Subject: "Your Chirpy login link",
+		Body:    "https://chirpy.example.com/app/login/magic?token=secret-token",
+	}
+	for _, tc := range []struct {
+		mailer   mail.LogMailer
+		wantBody bool
+	}{
+		{mail.LogMailer{}, false},
+		{mail.LogMailer{IncludeBody: true}, true},
+	} {
+		var out bytes.Buffer
+		logger, err := logging.New(&out, "info", logging.FormatJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := logging.WithLogger(context.Background(), logger)
+		if err := tc.mailer.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(out.String(), msg.To) {
+			t.Errorf("%+v: log does not name the recipient: %s", tc.mailer, out.String())
+		}
+		if got := strings.Contains(out.String(), "secret-token"); got != tc.wantBody {
+			t.Errorf("%+v: body logged = %v, want %v", tc.mailer, got, tc.wantBody)
+		}
+	}
+}
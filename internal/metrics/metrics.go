@@ -0,0 +1,138 @@
+// Package metrics collects the Prometheus metrics served at
+// /admin/metrics/prometheus: per-route HTTP request counts and latencies,
+// database query timings and connection pool stats, and Go runtime and
+// process stats.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chirpy"
+
+// Metrics owns a registry, so every Application gets its own set and tests
+// do not share counters through the global default registry.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	panics   prometheus.Counter
+	queries  *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_panics_total",
+			Help:      "Panics recovered while serving HTTP requests.",
+		}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by query name and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"query", "result"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.panics,
+		m.queries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Instrument counts and times requests to next under the given route
+// pattern. Routes are labelled by pattern rather than path so that
+// /api/chirps/{chirpId} is one series, not one per chirp.
+func (m *Metrics) Instrument(route string, next http.Handler) http.Handler {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerInFlight(m.inFlight,
+		promhttp.InstrumentHandlerDuration(m.duration.MustCurryWith(labels),
+			promhttp.InstrumentHandlerCounter(m.requests.MustCurryWith(labels), next),
+		),
+	)
+}
+
+// RecordPanic counts a panic recovered while serving a request.
+func (m *Metrics) RecordPanic() {
+	m.panics.Inc()
+}
+
+// ObserveQuery records one database query. It matches
+// database.QueryObserver.
+func (m *Metrics) ObserveQuery(name string, elapsed time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.queries.WithLabelValues(name, result).Observe(elapsed.Seconds())
+}
+
+// RegisterDB exports the connection pool stats of db.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Requests returns the number of requests served on the given routes.
+func (m *Metrics) Requests(routes ...string) (int, error) {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, family := range families {
+		if family.GetName() != namespace+"_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "route" && slices.Contains(routes, label.GetValue()) {
+					total += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return int(total), nil
+}
+
+// ResetRequests clears the HTTP request metrics. Prometheus handles counter
+// resets, so this is safe to call while being scraped.
+func (m *Metrics) ResetRequests() {
+	m.requests.Reset()
+	m.duration.Reset()
+}
@@ -0,0 +1,114 @@
+// Package ratelimit implements token bucket rate limiting. Buckets live in
+// a Store so instances behind a load balancer can share them; MemoryStore
+// keeps them in process, which suits a single instance.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Policy allows bursts of up to Requests requests, refilled evenly over
+// Period, e.g. 10 per minute is one new request every 6 seconds.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+}
+
+// rate is the number of tokens refilled per second.
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token. Reset is how long until the
+// bucket is full again; RetryAfter, set when the request is not allowed, is
+// how long until a token is available.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store takes a token from the bucket for key, creating it full if needed.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// sweepInterval bounds how often MemoryStore scans for buckets to forget.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// fullAt is when the bucket has refilled and can be forgotten.
+	fullAt time.Time
+}
+
+// MemoryStore keeps buckets in memory and forgets them once they refill.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// Now defaults to time.Now; tests replace it with a fixed clock.
+	Now func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		Now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	s.sweep(now)
+
+	capacity := float64(policy.Requests)
+	rate := policy.rate()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Limit: policy.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep forgets full buckets, at most once per sweepInterval; callers must
+// hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
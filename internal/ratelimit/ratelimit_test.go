@@ -0,0 +1,50 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/ratelimit"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2008, 1, 20, 12, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	policy := ratelimit.Policy{Requests: 3, Period: 30 * time.Second}
+
+	take := func(key string) ratelimit.Result {
+		t.Helper()
+		result, err := store.Take(ctx, key, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for i, remaining := range []int{2, 1, 0} {
+		result := take("walter")
+		if !result.Allowed || result.Remaining != remaining || result.Limit != 3 {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, remaining)
+		}
+	}
+	result := take("walter")
+	if result.Allowed || result.RetryAfter != 10*time.Second || result.Reset != 30*time.Second {
+		t.Errorf("request over the limit = %+v, want refused for 10s", result)
+	}
+	if result := take("jesse"); !result.Allowed {
+		t.Errorf("another key shares the bucket: %+v", result)
+	}
+
+	// one token is refilled every 10s
+	now = now.Add(10 * time.Second)
+	if result := take("walter"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after 10s = %+v, want one refilled token", result)
+	}
+	now = now.Add(time.Hour)
+	if result := take("walter"); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("after an hour = %+v, want a full bucket", result)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+type ChirpService struct {
+	store database.Store
+}
+
+func NewChirpService(store database.Store) *ChirpService {
+	return &ChirpService{store: store}
+}
+
+func (s *ChirpService) Create(ctx context.Context, userID uuid.UUID, body string) (database.Chirp, error) {
+	now := time.Now().UTC()
+	return s.store.CreateChirp(ctx, database.CreateChirpParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Body:      body,
+		UserID:    userID,
+	})
+}
+
+// ListOptions filters and orders List results. A zero AuthorID lists
+// chirps by everyone.
+type ListOptions struct {
+	AuthorID    uuid.UUID
+	NewestFirst bool
+}
+
+// List returns chirps oldest first unless opts.NewestFirst is set.
+func (s *ChirpService) List(ctx context.Context, opts ListOptions) ([]database.Chirp, error) {
+	var chirps []database.Chirp
+	var err error
+	if opts.AuthorID == uuid.Nil {
+		chirps, err = s.store.GetAllChirps(ctx)
+	} else {
+		chirps, err = s.store.GetChirpsByAuthor(ctx, opts.AuthorID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.NewestFirst {
+		sort.Slice(chirps, func(i, j int) bool {
+			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+		})
+	}
+	return chirps, nil
+}
+
+func (s *ChirpService) Get(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	return s.store.GetChirpById(ctx, chirpID)
+}
+
+// Delete removes a chirp on behalf of userID, who must be its author.
+func (s *ChirpService) Delete(ctx context.Context, userID, chirpID uuid.UUID) error {
+	return s.store.WithTx(ctx, func(q database.Querier) error {
+		chirp, err := q.GetChirpById(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		if chirp.UserID != userID {
+			return ErrForbidden
+		}
+		return q.DeleteChirp(ctx, chirpID)
+	})
+}
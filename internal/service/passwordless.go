@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+// CreateMagicLink stores a single-use login token for email and returns it
+// for delivery. Unknown emails get an account when the link is used.
+func (s *UserService) CreateMagicLink(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	token, tokenHash, err := auth.MakeMagicLinkToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.store.CreateMagicLinkToken(ctx, database.CreateMagicLinkTokenParams{
+		TokenHash: tokenHash,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, s.store.DeleteExpiredMagicLinkTokens(ctx)
+}
+
+// UserForMagicLink consumes a login token and returns its user, signing the
+// email up if needed. Consuming the token and creating the account happen in
+// one transaction, so a failed sign-up leaves the link usable.
+func (s *UserService) UserForMagicLink(ctx context.Context, token string) (database.User, error) {
+	var user database.User
+	err := s.store.WithTx(ctx, func(q database.Querier) error {
+		link, err := q.ConsumeMagicLinkToken(ctx, auth.HashMagicLinkToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidLoginLink
+		}
+		if err != nil {
+			return err
+		}
+
+		user, err = q.GetUserByEmail(ctx, link.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the link proved ownership of the email, so it is safe to sign up
+			user, err = createPasswordlessUser(ctx, q, link.Email)
+		}
+		return err
+	})
+	return user, err
+}
+
+// UserForIdentity finds the user linked to an external identity. Unknown
+// identities are linked to the account with the same email if the provider
+// verified that email, or get a new passwordless account.
+func (s *UserService) UserForIdentity(ctx context.Context, identity *auth.OIDCIdentity) (database.User, error) {
+	var user database.User
+	err := s.store.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		user, err = q.GetUserByIdentity(ctx, database.GetUserByIdentityParams{
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+		})
+		if err == nil || !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if identity.Email == "" {
+			return errors.New("identity provider did not return an email")
+		}
+
+		user, err = q.GetUserByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			// linking an unverified email would let anyone claim the account
+			if !identity.EmailVerified {
+				return ErrEmailTaken
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			user, err = createPasswordlessUser(ctx, q, identity.Email)
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		_, err = q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			ID:      uuid.New(),
+			UserID:  user.ID,
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+			Email:   identity.Email,
+		})
+		return err
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+func createPasswordlessUser(ctx context.Context, q database.Querier, email string) (database.User, error) {
+	now := time.Now().UTC()
+	return q.CreateUser(ctx, database.CreateUserParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          email,
+		HashedPassword: sql.NullString{},
+	})
+}
@@ -0,0 +1,19 @@
+// Package service holds Chirpy's business logic. Services depend on the
+// database.Store interface rather than on Postgres, so they run unchanged
+// against any Store implementation, and group multi-step writes in
+// transactions. HTTP concerns such as request decoding, throttling by
+// client IP and response shapes stay in internal/app.
+package service
+
+import "errors"
+
+// Errors returned by services for conditions the caller is expected to
+// handle. Storage errors, e.g. sql.ErrNoRows for a missing row, are
+// returned unchanged.
+var (
+	ErrInvalidCredentials = errors.New("incorrect email or password")
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrForbidden          = errors.New("not allowed to modify this resource")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	ErrInvalidLoginLink   = errors.New("invalid or expired login link")
+)
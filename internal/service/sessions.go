@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+// Session is the token pair handed out on login.
+type Session struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type SessionService struct {
+	store           database.Store
+	denylist        auth.TokenDenylist
+	jwtSecret       string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewSessionService(store database.Store, denylist auth.TokenDenylist, jwtSecret string, accessTokenTTL, refreshTokenTTL time.Duration) *SessionService {
+	return &SessionService{
+		store:           store,
+		denylist:        denylist,
+		jwtSecret:       jwtSecret,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// Issue starts a session for a user who has completed every login factor.
+func (s *SessionService) Issue(ctx context.Context, user database.User) (Session, error) {
+	accessToken, err := auth.MakeJWT(user.ID, s.jwtSecret, s.accessTokenTTL)
+	if err != nil {
+		return Session{}, fmt.Errorf("generating access token: %w", err)
+	}
+	refreshToken, err := auth.RefreshToken()
+	if err != nil {
+		return Session{}, err
+	}
+
+	_, err = s.store.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return Session{}, fmt.Errorf("storing refresh token: %w", err)
+	}
+	return Session{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh returns a new access token for a valid refresh token.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (string, error) {
+	user, err := s.store.GetUserForRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return "", ErrInvalidSession
+	}
+	return auth.MakeJWT(user.ID, s.jwtSecret, s.accessTokenTTL)
+}
+
+// Revoke ends a session. Access tokens are JWTs and get denylisted by jti,
+// anything else is treated as a refresh token.
+func (s *SessionService) Revoke(ctx context.Context, token string) error {
+	claims, err := auth.ParseJWT(token, s.jwtSecret)
+	if err != nil {
+		return s.store.RevokeRefreshToken(ctx, token)
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidSession
+	}
+	return s.denylist.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrEnrollmentNotFound  = errors.New("two-factor enrollment not found")
+	ErrInvalidCode         = errors.New("invalid two-factor code")
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+// Enrollment is what a user needs to set up an authenticator app. The
+// secret and recovery codes are only ever shown once.
+type Enrollment struct {
+	Secret        string
+	RecoveryCodes []string
+}
+
+type TwoFactorService struct {
+	store database.Store
+}
+
+func NewTwoFactorService(store database.Store) *TwoFactorService {
+	return &TwoFactorService{store: store}
+}
+
+// Enabled reports whether the user has a confirmed TOTP enrollment.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := s.store.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.ConfirmedAt.Valid, nil
+}
+
+// Enroll generates a pending TOTP secret and recovery codes. Re-enrolling
+// before confirmation replaces both; the swap is atomic so a failure never
+// leaves a secret without its recovery codes.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uuid.UUID) (Enrollment, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if enabled {
+		return Enrollment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+	recoveryCodes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	err = s.store.WithTx(ctx, func(q database.Querier) error {
+		_, err := q.UpsertUserTOTP(ctx, database.UpsertUserTOTPParams{
+			UserID: userID,
+			Secret: secret,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.DeleteRecoveryCodes(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, code := range recoveryCodes {
+			err = q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: auth.HashRecoveryCode(code),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Enrollment{}, err
+	}
+	return Enrollment{Secret: secret, RecoveryCodes: recoveryCodes}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves their
+// authenticator produces valid codes.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uuid.UUID, code string) error {
+	totp, err := s.store.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEnrollmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if totp.ConfirmedAt.Valid {
+		return ErrTwoFactorEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+	_, err = s.store.ConfirmUserTOTP(ctx, database.ConfirmUserTOTPParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	return err
+}
+
+// Verify checks a second factor: a TOTP code or, failing that, a single-use
+// recovery code.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uuid.UUID, code, recoveryCode string) error {
+	totp, err := s.store.GetUserTOTP(ctx, userID)
+	if err != nil || !totp.ConfirmedAt.Valid {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if code != "" {
+		step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return ErrInvalidCode
+		}
+		// a code may only be used once, even within its validity window
+		used, err := s.store.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: auth.HashRecoveryCode(recoveryCode),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidRecoveryCode
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maevlava/chirpy/internal/auth"
+	"github.com/maevlava/chirpy/internal/database"
+	"github.com/maevlava/chirpy/internal/logging"
+)
+
+type UserService struct {
+	store    database.Store
+	policy   auth.PasswordPolicy
+	hasher   *auth.PasswordHasher
+	denylist auth.TokenDenylist
+}
+
+func NewUserService(store database.Store, policy auth.PasswordPolicy, hasher *auth.PasswordHasher, denylist auth.TokenDenylist) *UserService {
+	return &UserService{
+		store:    store,
+		policy:   policy,
+		hasher:   hasher,
+		denylist: denylist,
+	}
+}
+
+// Register creates a user with a password. Password policy violations are
+// returned as the auth.ErrPassword* errors.
+func (s *UserService) Register(ctx context.Context, email, password string) (database.User, error) {
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+	now := time.Now().UTC()
+	return s.store.CreateUser(ctx, database.CreateUserParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+}
+
+// Update changes a user's email and password. Sessions issued with the old
+// password must not outlive it, so refresh tokens are revoked in the same
+// transaction and access tokens are denylisted once it commits.
+func (s *UserService) Update(ctx context.Context, userID uuid.UUID, email, password string) (database.User, error) {
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	var user database.User
+	err = s.store.WithTx(ctx, func(q database.Querier) error {
+		user, err = q.UpdateUser(ctx, database.UpdateUserParams{
+			ID:             userID,
+			Email:          email,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+		return q.RevokeUserRefreshTokens(ctx, userID)
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	err = s.denylist.RevokeUserTokens(ctx, userID, time.Now())
+	if err != nil {
+		return database.User{}, fmt.Errorf("revoking access tokens: %w", err)
+	}
+	return user, nil
+}
+
+// Authenticate checks an email and password. Unknown emails, passwordless
+// accounts and wrong passwords all yield ErrInvalidCredentials after the
+// same hashing work, so neither the error nor the timing tells them apart.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (database.User, error) {
+	user, err := s.store.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = s.hasher.CheckDummy(password)
+		return database.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	// passwordless accounts (magic link, external provider) cannot log in here
+	if !user.HashedPassword.Valid {
+		_ = s.hasher.CheckDummy(password)
+		return database.User{}, ErrInvalidCredentials
+	}
+	err = s.hasher.Check(user.HashedPassword.String, password)
+	if err != nil {
+		return database.User{}, ErrInvalidCredentials
+	}
+
+	// the plaintext is only available now, so upgrade outdated hashes in place
+	if s.hasher.NeedsRehash(user.HashedPassword.String) {
+		s.rehashPassword(ctx, user.ID, password)
+	}
+	return user, nil
+}
+
+func (s *UserService) Get(ctx context.Context, userID uuid.UUID) (database.User, error) {
+	return s.store.GetUserByID(ctx, userID)
+}
+
+func (s *UserService) UpgradeToChirpyRed(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.store.UpgradeUserToChirpyRed(ctx, userID)
+	return err
+}
+
+// DeleteAll removes every user and, through cascades, everything they own.
+func (s *UserService) DeleteAll(ctx context.Context) error {
+	return s.store.DeleteAllUsers(ctx)
+}
+
+func (s *UserService) hashPassword(password string) (sql.NullString, error) {
+	err := s.policy.Validate(password)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("hashing password: %w", err)
+	}
+	return sql.NullString{String: hashedPassword, Valid: true}, nil
+}
+
+func (s *UserService) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		logging.FromContext(ctx).Error("rehashing password", "user_id", userID, "err", err)
+		return
+	}
+	err = s.store.UpdateUserPasswordHash(ctx, database.UpdateUserPasswordHashParams{
+		ID:             userID,
+		HashedPassword: sql.NullString{String: hashedPassword, Valid: true},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("storing rehashed password", "user_id", userID, "err", err)
+	}
+}
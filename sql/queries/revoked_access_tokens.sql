@@ -0,0 +1,31 @@
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsAccessTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_access_tokens
+    WHERE jti = $1
+      AND expires_at > NOW()
+);
+
+-- name: RevokeUserAccessTokens :exec
+INSERT INTO user_token_revocations (user_id, revoked_before, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before),
+    expires_at = GREATEST(user_token_revocations.expires_at, EXCLUDED.expires_at);
+
+-- name: GetUserAccessTokensRevokedBefore :one
+SELECT revoked_before FROM user_token_revocations
+WHERE user_id = $1
+  AND expires_at > NOW();
+
+-- name: DeleteExpiredAccessTokenRevocations :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at <= NOW();
+
+-- name: DeleteExpiredUserTokenRevocations :exec
+DELETE FROM user_token_revocations
+WHERE expires_at <= NOW();
@@ -0,0 +1,11 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, user_id, issuer, subject, email)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUserByIdentity :one
+SELECT users.*
+FROM users
+INNER JOIN user_identities ON users.id = user_identities.user_id
+WHERE user_identities.issuer = $1
+  AND user_identities.subject = $2;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users ALTER COLUMN hashed_password DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN hashed_password DROP DEFAULT;
+UPDATE users SET hashed_password = NULL WHERE hashed_password = 'unset';
+
+CREATE TABLE magic_link_tokens(
+    token_hash TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE magic_link_tokens;
+
+UPDATE users SET hashed_password = 'unset' WHERE hashed_password IS NULL;
+ALTER TABLE users ALTER COLUMN hashed_password SET DEFAULT 'unset';
+ALTER TABLE users ALTER COLUMN hashed_password SET NOT NULL;
@@ -0,0 +1,28 @@
+// Package schema embeds the goose migrations in this directory so the
+// server binary can apply them without the source tree.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds the Postgres migrations.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// SQLiteFS holds the SQLite translations of the migrations in FS, with the
+// same version numbers.
+var SQLiteFS = mustSub(sqliteFiles, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
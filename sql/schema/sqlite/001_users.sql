@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQLite translation of ../001_users.sql. UUIDs are stored as TEXT and
+-- timestamps as TEXT in UTC, "YYYY-MM-DD HH:MM:SS.ffffff", which sorts and
+-- compares like the Postgres TIMESTAMP it replaces.
+CREATE TABLE users(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT UNIQUE NOT NULL
+);
+-- +goose Down
+DROP TABLE users;
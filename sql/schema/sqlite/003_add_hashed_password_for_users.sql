@@ -0,0 +1,5 @@
+-- +goose Up
+-- nullable from the start: SQLite cannot relax NOT NULL later, see 010
+ALTER TABLE users ADD COLUMN hashed_password TEXT;
+-- +goose Down
+ALTER TABLE users DROP COLUMN hashed_password;
@@ -0,0 +1,4 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_chirpy_red;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE revoked_access_tokens(
+    jti TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE TABLE user_token_revocations(
+    user_id TEXT PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose Down
+DROP TABLE user_token_revocations;
+DROP TABLE revoked_access_tokens;
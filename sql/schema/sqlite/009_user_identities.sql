@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE user_identities(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    UNIQUE (issuer, subject),
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose Down
+DROP TABLE user_identities;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- hashed_password is already nullable here, see 003
+CREATE TABLE magic_link_tokens(
+    token_hash TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+-- +goose Down
+DROP TABLE magic_link_tokens;